@@ -0,0 +1,252 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	loginCookie       = "listboard_login"
+	loginDuration     = 30 * 24 * time.Hour
+	minPasswordLength = 8
+)
+
+var usernameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
+
+type Account struct {
+	Id       int       `db:"id"`
+	DomainId int       `db:"domain_id"`
+	Username string    `db:"username"`
+	Password string    `db:"password"`
+	Created  time.Time `db:"created"`
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+func checkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// newToken returns a random token and its hash. Only the hash is stored.
+func newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (m *Model) addAccount(a *Account) (int, error) {
+	res, err := m.db.NamedExec(`INSERT INTO account (
+			domain_id,
+			username,
+			password,
+			created
+		) VALUES (
+			:domain_id,
+			:username,
+			:password,
+			:created
+		)`,
+		map[string]interface{}{
+			"domain_id": a.DomainId,
+			"username":  a.Username,
+			"password":  a.Password,
+			"created":   time.Now(),
+		})
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	id, err = res.LastInsertId()
+	return int(id), err
+}
+
+func (m *Model) getAccountByUsername(domainId int, username string) (*Account, error) {
+	var a Account
+	err := m.db.Get(&a, "SELECT * FROM account WHERE domain_id=$1 AND username=$2 COLLATE NOCASE", domainId, username)
+	return &a, err
+}
+
+func (m *Model) addLogin(accountId int, tokenHash string, expires time.Time) error {
+	_, err := m.db.Exec("INSERT INTO login (token, account_id, expires) VALUES ($1, $2, $3)", tokenHash, accountId, expires)
+	return err
+}
+
+func (m *Model) getLoginAccount(domainId int, tokenHash string) (*Account, error) {
+	var a Account
+	err := m.db.Get(&a, `SELECT account.* FROM login
+		JOIN account ON account.id = login.account_id
+		WHERE login.token=$1 AND login.expires > $2 AND account.domain_id=$3`, tokenHash, time.Now(), domainId)
+	return &a, err
+}
+
+func (m *Model) deleteLogin(tokenHash string) error {
+	_, err := m.db.Exec("DELETE FROM login WHERE token=$1", tokenHash)
+	return err
+}
+
+// currentAccount returns the logged in account or nil for anonymous visitors
+func (l *ListBoard) currentAccount(r *http.Request, domainId int) *Account {
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	account, err := l.m.getLoginAccount(domainId, hashToken(cookie.Value))
+	if err != nil {
+		return nil
+	}
+	return account
+}
+
+// session creates a template session for the current visitor
+func (l *ListBoard) session(w http.ResponseWriter, r *http.Request, sc *SiteConfig, ln *Language) *Session {
+	s := NewSession(sc, ln)
+	account := l.currentAccount(r, sc.DomainId)
+	s.Set("Account", account)
+	s.Set("Moderator", account != nil && sc.isModerator(Credentials{AccountId: account.Id, Username: account.Username}))
+	s.Set("Csrf", csrfToken(w, r))
+	return s
+}
+
+func (l *ListBoard) startLogin(w http.ResponseWriter, r *http.Request, account *Account) error {
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(loginDuration)
+	if err := l.m.addLogin(account.Id, tokenHash, expires); err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (l *ListBoard) registerHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+
+	var errors ValidationErrors
+	username := r.FormValue("username")
+	if r.Method == "POST" {
+		if !inHoneypot(r.FormValue("name")) {
+			password := r.FormValue("password")
+			if !l.sg.CanPost(r.RemoteAddr) {
+				errors = append(errors, tr.Lang("Please wait before posting again"))
+			}
+			if !usernameRe.MatchString(username) {
+				errors = append(errors, tr.Lang("Username must be 3 to 32 letters, digits, dashes or underscores"))
+			}
+			if len(password) < minPasswordLength {
+				errors = append(errors, tr.Lang("Password must be at least 8 characters long"))
+			}
+			if password != r.FormValue("password2") {
+				errors = append(errors, tr.Lang("Passwords do not match"))
+			}
+			if len(errors) == 0 {
+				if _, err := l.m.getAccountByUsername(sc.DomainId, username); err != sql.ErrNoRows {
+					if err != nil {
+						return err
+					}
+					errors = append(errors, tr.Lang("Username is already taken"))
+				}
+			}
+			if len(errors) == 0 {
+				hash, err := hashPassword(password)
+				if err != nil {
+					return err
+				}
+				account := &Account{DomainId: sc.DomainId, Username: username, Password: hash}
+				if account.Id, err = l.m.addAccount(account); err != nil {
+					return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+				}
+				if err := l.startLogin(w, r, account); err != nil {
+					return err
+				}
+				http.Redirect(w, r, "/", http.StatusFound)
+				return nil
+			}
+		}
+	}
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Username", username)
+	s.Set("Register", true)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath("", s.Lang("Register"))
+	s.Set("Subtitle", s.Lang("Register"))
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("login.html"))
+}
+
+func (l *ListBoard) loginHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+
+	var errors ValidationErrors
+	username := r.FormValue("username")
+	if r.Method == "POST" {
+		if !inHoneypot(r.FormValue("name")) {
+			account, err := l.m.getAccountByUsername(sc.DomainId, username)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if err == nil && checkPassword(account.Password, r.FormValue("password")) {
+				if err := l.startLogin(w, r, account); err != nil {
+					return err
+				}
+				http.Redirect(w, r, "/", http.StatusFound)
+				return nil
+			}
+			errors = append(errors, tr.Lang("Wrong username or password"))
+		}
+	}
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Username", username)
+	s.Set("Register", false)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath("", s.Lang("Login"))
+	s.Set("Subtitle", s.Lang("Login"))
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("login.html"))
+}
+
+func (l *ListBoard) logoutHandler(w http.ResponseWriter, r *http.Request) error {
+	if cookie, err := r.Cookie(loginCookie); err == nil {
+		if err := l.m.deleteLogin(hashToken(cookie.Value)); err != nil {
+			return err
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, "/", http.StatusFound)
+	return nil
+}
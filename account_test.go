@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestPasswordHash(t *testing.T) {
+	t.Run("hashed password can be checked", func(t *testing.T) {
+		hash, err := hashPassword("longpassword")
+		if err != nil {
+			t.Fatalf("hashPassword() error = %v", err)
+		}
+		if !checkPassword(hash, "longpassword") {
+			t.Errorf("Expected password to match its hash")
+		}
+		if checkPassword(hash, "otherpassword") {
+			t.Errorf("Expected wrong password not to match")
+		}
+	})
+}
+
+func TestNewToken(t *testing.T) {
+	t.Run("token hash matches the token", func(t *testing.T) {
+		token, hash, err := newToken()
+		if err != nil {
+			t.Fatalf("newToken() error = %v", err)
+		}
+		if hashToken(token) != hash {
+			t.Errorf("hashToken() does not match the returned hash")
+		}
+		if token == hash {
+			t.Errorf("Expected the token to be hashed")
+		}
+	})
+}
@@ -0,0 +1,814 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	scopeRead     = "read"
+	scopePost     = "post"
+	scopeVote     = "vote"
+	scopeModerate = "moderate"
+)
+
+var apiScopes = []string{scopeRead, scopePost, scopeVote, scopeModerate}
+
+// ApiToken authenticates scripts using the API. Tokens belong either to an
+// account or to a tripcode.
+type ApiToken struct {
+	Id        int       `db:"id"`
+	DomainId  int       `db:"domain_id"`
+	Token     string    `db:"token"`
+	Name      string    `db:"name"`
+	Scopes    string    `db:"scopes"`
+	AccountId int       `db:"account_id"`
+	Username  string    `db:"username"`
+	Tripcode  string    `db:"tripcode"`
+	Created   time.Time `db:"created"`
+	Used      time.Time `db:"used"`
+}
+
+type ApiTokenList []ApiToken
+
+type apiPost struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Vote  int    `json:"vote"`
+
+	Ranking  string   `json:"ranking"`
+	Kind     string   `json:"kind"`
+	Options  []string `json:"options"`
+	Multiple bool     `json:"multiple"`
+	Closes   string   `json:"closes"`
+	Tags     []string `json:"tags"`
+
+	Items        string `json:"items"`
+	Voting       string `json:"voting"`
+	CommentsOnly bool   `json:"comments_only"`
+	MaxItems     int    `json:"max_items"`
+}
+
+type apiNodes struct {
+	Node  *Node     `json:"node,omitempty"`
+	Items *NodeList `json:"items,omitempty"`
+	// Next is the cursor of the next page
+	Next string `json:"next,omitempty"`
+}
+
+type apiErrors struct {
+	Errors ValidationErrors `json:"errors"`
+}
+
+func (t *ApiToken) HasScope(scope string) bool {
+	for _, s := range strings.Split(t.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *ApiToken) credentials() Credentials {
+	return Credentials{
+		Tripcode:  t.Tripcode,
+		AccountId: t.AccountId,
+		Username:  t.Username,
+	}
+}
+
+func (m *Model) addApiToken(t *ApiToken) (int, error) {
+	res, err := m.db.NamedExec(`INSERT INTO api_token (
+			domain_id,
+			token,
+			name,
+			scopes,
+			account_id,
+			tripcode,
+			created,
+			used
+		) VALUES (
+			:domain_id,
+			:token,
+			:name,
+			:scopes,
+			:account_id,
+			:tripcode,
+			:created,
+			:used
+		)`,
+		map[string]interface{}{
+			"domain_id":  t.DomainId,
+			"token":      t.Token,
+			"name":       t.Name,
+			"scopes":     t.Scopes,
+			"account_id": t.AccountId,
+			"tripcode":   t.Tripcode,
+			"created":    time.Now(),
+			"used":       time.Now(),
+		})
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	id, err = res.LastInsertId()
+	return int(id), err
+}
+
+func (m *Model) getApiToken(domainId int, tokenHash string) (*ApiToken, error) {
+	var t ApiToken
+	err := m.db.Get(&t, `SELECT api_token.*, COALESCE(account.username, '') AS username FROM api_token
+		LEFT JOIN account ON account.id = api_token.account_id
+		WHERE api_token.domain_id=$1 AND api_token.token=$2`, domainId, tokenHash)
+	return &t, err
+}
+
+// getApiTokens returns the tokens owned by the account or the tripcode
+func (m *Model) getApiTokens(domainId int, c Credentials) (*ApiTokenList, error) {
+	var tl ApiTokenList
+	err := m.db.Select(&tl, `SELECT api_token.*, COALESCE(account.username, '') AS username FROM api_token
+		LEFT JOIN account ON account.id = api_token.account_id
+		WHERE api_token.domain_id=$1 AND (
+			(api_token.account_id != 0 AND api_token.account_id=$2)
+			OR (api_token.account_id = 0 AND api_token.tripcode != '' AND api_token.tripcode=$3)
+		)
+		ORDER BY api_token.created DESC`, domainId, c.AccountId, c.Tripcode)
+	return &tl, err
+}
+
+func (m *Model) touchApiToken(id int) error {
+	_, err := m.db.Exec("UPDATE api_token SET used=$1 WHERE id=$2", time.Now(), id)
+	return err
+}
+
+func (m *Model) deleteApiToken(domainId, id int, c Credentials) error {
+	_, err := m.db.Exec(`DELETE FROM api_token WHERE domain_id=$1 AND id=$2 AND (
+			(account_id != 0 AND account_id=$3)
+			OR (account_id = 0 AND tripcode != '' AND tripcode=$4)
+		)`, domainId, id, c.AccountId, c.Tripcode)
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// apiToken authenticates the bearer token of the request and checks its scope
+func (l *ListBoard) apiToken(r *http.Request, domainId int, scope string) (*ApiToken, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, HTTPError{Message: "Missing API token", Code: http.StatusUnauthorized}
+	}
+	token, err := l.m.getApiToken(domainId, hashToken(strings.TrimPrefix(header, "Bearer ")))
+	if err == sql.ErrNoRows {
+		return nil, HTTPError{Err: err, Message: "Invalid API token", Code: http.StatusUnauthorized}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !token.HasScope(scope) {
+		return nil, HTTPError{Message: "API token is missing the " + scope + " scope", Code: http.StatusForbidden}
+	}
+	// Reads and writes are limited separately
+	sg := l.apiSg
+	if scope == scopeRead {
+		sg = l.apiReadSg
+	}
+	if !sg.CanPost("token:" + strconv.Itoa(token.Id)) {
+		return nil, HTTPError{Message: "Too many requests", Code: http.StatusTooManyRequests}
+	}
+	return token, l.m.touchApiToken(token.Id)
+}
+
+// apiNode decodes the posted JSON into a new node owned by the token
+func (l *ListBoard) apiNode(r *http.Request, sc *SiteConfig, token *ApiToken, parentId, level int, ln *Language) (Node, ValidationErrors) {
+	var post apiPost
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		return Node{}, ValidationErrors{err.Error()}
+	}
+	node := Node{
+		ParentId:  parentId,
+		DomainId:  token.DomainId,
+		Title:     strings.TrimSpace(post.Title),
+		Tripcode:  token.Tripcode,
+		AccountId: token.AccountId,
+		Username:  token.Username,
+		Body:      post.Body,
+		Status:    statusEnabled,
+		Level:     level,
+	}
+	if post.Vote > 0 {
+		node.Vote = 1
+	} else if post.Vote < 0 {
+		node.Vote = -1
+	}
+	if level == levelRoot {
+		node.Ranking = validRanking(post.Ranking)
+		node.Kind = validKind(post.Kind)
+		if node.IsPoll() {
+			node.Options = parsePollOptions(strings.Join(post.Options, "\n"))
+		}
+		node.Settings = newListSettings(node.IsPoll() && post.Multiple, post.Items, post.Voting,
+			post.CommentsOnly, post.MaxItems).encode()
+		node.Tags = parseTags(strings.Join(post.Tags, ","))
+		closes, errors := parseCloses(post.Closes, ln)
+		if len(errors) != 0 {
+			return node, errors
+		}
+		node.Closes = closes
+	}
+	return node, l.validateNode(&node, sc, ln)
+}
+
+func (l *ListBoard) apiCreated(w http.ResponseWriter, domainId, id int) error {
+	node, err := l.m.getNode(domainId, id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, apiNodes{Node: node})
+}
+
+func (l *ListBoard) apiListsHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	if r.Method == "POST" {
+		token, err := l.apiToken(r, sc.DomainId, scopePost)
+		if err != nil {
+			return err
+		}
+		node, errors := l.apiNode(r, sc, token, 0, levelRoot, tr)
+		if len(errors) != 0 {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+		}
+		id, err := l.m.addList(&node, titledItems(node.Options))
+		if err != nil {
+			return err
+		}
+		return l.apiCreated(w, sc.DomainId, id)
+	}
+	if _, err := l.apiToken(r, sc.DomainId, scopeRead); err != nil {
+		return err
+	}
+	q := r.URL.Query()
+	f := parseIndexFilter(q)
+	if _, ok := keysets[f.order()]; ok && q.Get("page") == "" {
+		lists, next, err := l.m.getListsPage(sc.DomainId, f, itemsPerPage, q.Get("cursor"))
+		if err != nil {
+			return apiCursorError(err)
+		}
+		return writeJSON(w, http.StatusOK, apiNodes{Items: lists, Next: next})
+	}
+	page := getPageNumber(q.Get("page"))
+	return writeJSON(w, http.StatusOK, apiNodes{
+		Items: l.m.mustGetLists(sc.DomainId, f, itemsPerPage, page*itemsPerPage),
+	})
+}
+
+// apiCursorError reports invalid cursors as bad requests
+func apiCursorError(err error) error {
+	if err == errInvalidCursor {
+		return HTTPError{Err: err, Message: err.Error(), Code: http.StatusBadRequest}
+	}
+	return err
+}
+
+func (l *ListBoard) apiListHandler(w http.ResponseWriter, r *http.Request) error {
+	listId, err := strconv.Atoi(mux.Vars(r)["listId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	scope := scopeRead
+	if r.Method == "POST" {
+		scope = scopePost
+	}
+	token, err := l.apiToken(r, sc.DomainId, scope)
+	if err != nil {
+		return err
+	}
+	list, err := l.m.getNode(sc.DomainId, listId)
+	if err == sql.ErrNoRows && r.Method == "GET" && l.redirectMerged(w, r, sc, listId, "/api/list/", "") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if r.Method == "POST" {
+		if list.IsPoll() {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{tr.Lang("Polls don't accept new options")}})
+		}
+		if errors := closedErrors(list, tr); len(errors) != 0 {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+		}
+		errors, err := l.itemErrors(list, token.credentials(), tr)
+		if err != nil {
+			return err
+		}
+		if len(errors) != 0 {
+			return writeJSON(w, http.StatusForbidden, apiErrors{errors})
+		}
+		node, errors := l.apiNode(r, sc, token, listId, levelList, tr)
+		if len(errors) != 0 {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+		}
+		id, err := l.m.addNode(&node)
+		if err != nil {
+			return err
+		}
+		return l.apiCreated(w, sc.DomainId, id)
+	}
+	if list.Tags, err = l.m.getTagNames(sc.DomainId, listId); err != nil {
+		return err
+	}
+	q := r.URL.Query()
+	order := rankingOrder(list.Ranking, sc.Ranking)
+	if q.Get("page") == "" {
+		items, next, err := l.m.getChildNodesPage(sc.DomainId, listId, itemsPerPage, order, q.Get("cursor"))
+		if err != nil {
+			return apiCursorError(err)
+		}
+		return writeJSON(w, http.StatusOK, apiNodes{Node: list, Items: items, Next: next})
+	}
+	page := getPageNumber(q.Get("page"))
+	return writeJSON(w, http.StatusOK, apiNodes{
+		Node:  list,
+		Items: l.m.mustGetChildNodes(sc.DomainId, listId, itemsPerPage, page*itemsPerPage, order),
+	})
+}
+
+func (l *ListBoard) apiVoteHandler(w http.ResponseWriter, r *http.Request) error {
+	itemId, err := strconv.Atoi(mux.Vars(r)["itemId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	scope := scopeRead
+	if r.Method == "POST" {
+		scope = scopeVote
+	}
+	token, err := l.apiToken(r, sc.DomainId, scope)
+	if err != nil {
+		return err
+	}
+	item, err := l.m.getNode(sc.DomainId, itemId)
+	if err != nil {
+		return err
+	}
+	if r.Method == "POST" {
+		node, errors := l.apiNode(r, sc, token, itemId, levelVote, tr)
+		if len(errors) != 0 {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+		}
+		list, err := l.m.getNode(sc.DomainId, item.ParentId)
+		if err != nil {
+			return err
+		}
+		if errors := closedErrors(list, tr); len(errors) != 0 {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+		}
+		// API tokens always belong to a tripcode or an account
+		if errors := votingErrors(list, true, tr); len(errors) != 0 {
+			return writeJSON(w, http.StatusForbidden, apiErrors{errors})
+		}
+		if !list.ShowItemVotes() {
+			// only comments, the items are voted in the list or not at all
+			node.Vote = 0
+		}
+		id, err := l.m.addNode(&node)
+		if err != nil {
+			return err
+		}
+		if err := l.m.Vote(sc.DomainId, node.Vote, id, itemId, item.ParentId); err != nil {
+			return err
+		}
+		return l.apiCreated(w, sc.DomainId, id)
+	}
+	return writeJSON(w, http.StatusOK, apiNodes{
+		Node:  item,
+		Items: l.m.mustGetChildNodes(sc.DomainId, itemId, itemsPerPage, 0, "id DESC"),
+	})
+}
+
+type apiPollVote struct {
+	Options []int `json:"options"`
+}
+
+func (l *ListBoard) apiPollHandler(w http.ResponseWriter, r *http.Request) error {
+	listId, err := strconv.Atoi(mux.Vars(r)["listId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	token, err := l.apiToken(r, sc.DomainId, scopeVote)
+	if err != nil {
+		return err
+	}
+	list, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	if !list.IsPoll() {
+		return HTTPError{Message: "Not a poll", Code: http.StatusNotFound}
+	}
+	var vote apiPollVote
+	if err := json.NewDecoder(r.Body).Decode(&vote); err != nil {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{err.Error()}})
+	}
+	chosen := make([]string, len(vote.Options))
+	for i, id := range vote.Options {
+		chosen[i] = strconv.Itoa(id)
+	}
+	if errors := l.votePoll(list, chosen, token.voterKey(r), true, tr); len(errors) != 0 {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+	}
+	if list, err = l.m.getNode(sc.DomainId, listId); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, apiNodes{
+		Node:  list,
+		Items: l.m.mustGetChildNodes(sc.DomainId, listId, itemsPerPage, 0, "id"),
+	})
+}
+
+type apiBallot struct {
+	Items []int `json:"items"`
+}
+
+type apiRankedResults struct {
+	Node    *Node          `json:"node"`
+	Ballots int            `json:"ballots"`
+	Results []RankedResult `json:"results"`
+}
+
+func (l *ListBoard) apiBallotHandler(w http.ResponseWriter, r *http.Request) error {
+	listId, err := strconv.Atoi(mux.Vars(r)["listId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	scope := scopeRead
+	if r.Method == "POST" {
+		scope = scopeVote
+	}
+	token, err := l.apiToken(r, sc.DomainId, scope)
+	if err != nil {
+		return err
+	}
+	list, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	if !list.IsRanked() {
+		return HTTPError{Message: "Not a ranked choice list", Code: http.StatusNotFound}
+	}
+	code := http.StatusOK
+	if r.Method == "POST" {
+		if errors := closedErrors(list, tr); len(errors) != 0 {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+		}
+		if errors := votingErrors(list, true, tr); len(errors) != 0 {
+			return writeJSON(w, http.StatusForbidden, apiErrors{errors})
+		}
+		var post apiBallot
+		if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{err.Error()}})
+		}
+		items, err := l.m.getRankedItems(sc.DomainId, listId)
+		if err != nil {
+			return err
+		}
+		ranks := make(map[int]string)
+		for i, id := range post.Items {
+			ranks[id] = strconv.Itoa(i + 1)
+		}
+		if len(post.Items) != len(*items) {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{tr.Lang("Please, rank all items")}})
+		}
+		ballot, errors := parseBallot(*items, ranks, tr)
+		if len(errors) != 0 {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+		}
+		if err := l.m.setBallot(sc.DomainId, listId, token.voterKey(r), ballot); err != nil {
+			return err
+		}
+		if list, err = l.m.getNode(sc.DomainId, listId); err != nil {
+			return err
+		}
+		code = http.StatusCreated
+	}
+	results, ballots, err := l.getRankedResults(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, code, apiRankedResults{Node: list, Ballots: ballots, Results: results})
+}
+
+type apiClose struct {
+	Closed bool `json:"closed"`
+}
+
+func (l *ListBoard) apiCloseHandler(w http.ResponseWriter, r *http.Request) error {
+	listId, err := strconv.Atoi(mux.Vars(r)["listId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	token, err := l.apiToken(r, sc.DomainId, scopeModerate)
+	if err != nil {
+		return err
+	}
+	list, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	var post apiClose
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{err.Error()}})
+	}
+	changed, err := l.m.setClosed(list, post.Closed, token.credentials())
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return HTTPError{Message: "List does not belong to the API token", Code: http.StatusForbidden}
+	}
+	if list, err = l.m.getNode(sc.DomainId, listId); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, apiNodes{Node: list})
+}
+
+type apiDone struct {
+	Done bool `json:"done"`
+}
+
+// apiDoneHandler marks a checklist item done or not done
+func (l *ListBoard) apiDoneHandler(w http.ResponseWriter, r *http.Request) error {
+	itemId, err := strconv.Atoi(mux.Vars(r)["itemId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	token, err := l.apiToken(r, sc.DomainId, scopeModerate)
+	if err != nil {
+		return err
+	}
+	item, err := l.m.getNode(sc.DomainId, itemId)
+	if err != nil {
+		return err
+	}
+	if item.Level != levelList {
+		return HTTPError{Message: "Only items can be marked done", Code: http.StatusNotFound}
+	}
+	list, err := l.m.getNode(sc.DomainId, item.ParentId)
+	if err != nil {
+		return err
+	}
+	if errors := l.doneErrors(list, token.credentials(), tr); len(errors) != 0 {
+		return writeJSON(w, http.StatusForbidden, apiErrors{errors})
+	}
+	var post apiDone
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{err.Error()}})
+	}
+	if _, err := l.m.setDone(item, post.Done, token.credentials()); err != nil {
+		return err
+	}
+	if item, err = l.m.getNode(sc.DomainId, itemId); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, apiNodes{Node: item})
+}
+
+type apiPin struct {
+	Pinned   int    `json:"pinned"`
+	Expires  string `json:"expires"`
+	Featured int    `json:"featured"`
+}
+
+// apiPinHandler sets the positions of a list in the pinned and featured
+// sections or the position of a pinned item in its list
+func (l *ListBoard) apiPinHandler(w http.ResponseWriter, r *http.Request) error {
+	listId, err := strconv.Atoi(mux.Vars(r)["listId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	token, err := l.apiToken(r, sc.DomainId, scopeModerate)
+	if err != nil {
+		return err
+	}
+	list, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	if list.Level != levelRoot && list.Level != levelList {
+		return HTTPError{Message: "Only lists and items can be pinned", Code: http.StatusNotFound}
+	}
+	if !l.canPin(sc, list, token.credentials()) {
+		return HTTPError{Message: pinErrors(list, tr)[0], Code: http.StatusForbidden}
+	}
+	var post apiPin
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{err.Error()}})
+	}
+	expires, err := parseLocalTime(post.Expires)
+	if err != nil {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{tr.Lang("Invalid expiry time")}})
+	}
+	if list.Level == levelList {
+		expires, post.Featured = nil, 0
+	}
+	if err := l.m.setPin(list, validPosition(post.Pinned), expires, validPosition(post.Featured)); err != nil {
+		return err
+	}
+	if list, err = l.m.getNode(sc.DomainId, listId); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, apiNodes{Node: list})
+}
+
+type apiMove struct {
+	Target int `json:"target"`
+}
+
+// apiMoveHandler moves an item to another list or merges a list into
+// another one
+func (l *ListBoard) apiMoveHandler(w http.ResponseWriter, r *http.Request) error {
+	nodeId, err := strconv.Atoi(mux.Vars(r)["nodeId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	token, err := l.apiToken(r, sc.DomainId, scopeModerate)
+	if err != nil {
+		return err
+	}
+	node, err := l.m.getNode(sc.DomainId, nodeId)
+	if err != nil {
+		return err
+	}
+	source := node
+	if node.Level == levelList {
+		if source, err = l.m.getNode(sc.DomainId, node.ParentId); err != nil {
+			return err
+		}
+	} else if node.Level != levelRoot {
+		return HTTPError{Message: "Only lists and items can be moved", Code: http.StatusNotFound}
+	}
+	var post apiMove
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{err.Error()}})
+	}
+	target, err := l.m.getNode(sc.DomainId, post.Target)
+	if err == sql.ErrNoRows {
+		target = &Node{}
+	} else if err != nil {
+		return err
+	}
+	if errors := moveErrors(source, target, tr); len(errors) != 0 {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+	}
+	if !l.canModerate(sc, token.credentials(), source, target) {
+		return HTTPError{Message: "Lists do not belong to the API token", Code: http.StatusForbidden}
+	}
+	if node.Level == levelRoot {
+		err = l.m.mergeLists(node, target)
+	} else {
+		err = l.m.moveItem(node, target)
+	}
+	if err != nil {
+		return err
+	}
+	if target, err = l.m.getNode(sc.DomainId, target.Id); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, apiNodes{Node: target})
+}
+
+func (l *ListBoard) apiNodeHandler(w http.ResponseWriter, r *http.Request) error {
+	nodeId, err := strconv.Atoi(mux.Vars(r)["nodeId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	token, err := l.apiToken(r, sc.DomainId, scopeModerate)
+	if err != nil {
+		return err
+	}
+	node, err := l.m.getNode(sc.DomainId, nodeId)
+	if err != nil {
+		return err
+	}
+	if errors, err := l.deleteErrors(node, l.tp.Get(sc.Language)); err != nil {
+		return err
+	} else if len(errors) != 0 {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+	}
+	var deleted bool
+	if c := token.credentials(); sc.isModerator(c) {
+		deleted, err = l.m.deleteAnyNode(node)
+	} else {
+		deleted, err = l.m.deleteNode(node, c)
+	}
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return HTTPError{Message: "Node does not belong to the API token", Code: http.StatusForbidden}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// tokensHandler lists, creates and revokes the API tokens of the logged in
+// account or of the tripcode password. The password is never rendered back,
+// it's asked again for every action.
+func (l *ListBoard) tokensHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+
+	var errors ValidationErrors
+	var created string
+	var tokens *ApiTokenList
+	c := l.credentials(r, sc.DomainId)
+	if c.AccountId != 0 || c.Tripcode != "" {
+		if r.Method == "POST" && !inHoneypot(r.FormValue("name")) {
+			switch r.FormValue("action") {
+			case "create":
+				var scopes []string
+				for _, scope := range apiScopes {
+					if r.FormValue("scope_"+scope) != "" {
+						scopes = append(scopes, scope)
+					}
+				}
+				title := strings.TrimSpace(r.FormValue("title"))
+				if len(title) < 3 {
+					errors = append(errors, tr.Lang("Title must be at least 3 characters long"))
+				}
+				if len(scopes) == 0 {
+					errors = append(errors, tr.Lang("Select at least one scope"))
+				}
+				if len(errors) == 0 {
+					token, tokenHash, err := newToken()
+					if err != nil {
+						return err
+					}
+					t := &ApiToken{
+						DomainId:  sc.DomainId,
+						Token:     tokenHash,
+						Name:      title,
+						Scopes:    strings.Join(scopes, ","),
+						AccountId: c.AccountId,
+					}
+					if c.AccountId == 0 {
+						t.Tripcode = c.Tripcode
+					}
+					if _, err := l.m.addApiToken(t); err != nil {
+						return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+					}
+					created = token
+				}
+			case "revoke":
+				id, err := strconv.Atoi(r.FormValue("id"))
+				if err != nil {
+					return err
+				}
+				if err := l.m.deleteApiToken(sc.DomainId, id, c); err != nil {
+					return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+				}
+			}
+		}
+		var err error
+		if tokens, err = l.m.getApiTokens(sc.DomainId, c); err != nil {
+			return err
+		}
+	} else if r.Method == "POST" {
+		errors = append(errors, tr.Lang("Login or enter your tripcode password"))
+	}
+
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Tokens", tokens)
+	s.Set("Created", created)
+	s.Set("Scopes", apiScopes)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath("", s.Lang("API tokens"))
+	s.Set("Subtitle", s.Lang("API tokens"))
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("tokens.html"))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestApiTokenHasScope(t *testing.T) {
+	token := &ApiToken{Scopes: "read,vote"}
+	tests := []struct {
+		scope string
+		want  bool
+	}{
+		{scopeRead, true},
+		{scopeVote, true},
+		{scopePost, false},
+		{scopeModerate, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			if got := token.HasScope(tt.scope); got != tt.want {
+				t.Errorf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+// newApiTestBoard returns a board with the API limits of the tests
+func newApiTestBoard(t *testing.T, sc SiteConfig) *ListBoard {
+	sc.DomainId = 1
+	return &ListBoard{
+		config:    &Config{Servers: map[string]SiteConfig{"": sc}},
+		m:         newTestModel(t),
+		tp:        NewTransPool(t.TempDir() + "/"),
+		apiSg:     NewSpamGuard("1h"),
+		apiReadSg: NewSpamGuard("1h"),
+	}
+}
+
+// addTestToken stores a token of the tripcode and returns its bearer value
+func addTestToken(t *testing.T, l *ListBoard, tripcode, scopes string) (string, int) {
+	token, tokenHash, err := newToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := l.m.addApiToken(&ApiToken{DomainId: 1, Token: tokenHash, Name: "test", Scopes: scopes, Tripcode: tripcode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, id
+}
+
+func apiRequest(l *ListBoard, method, target, token string, vars map[string]string) int {
+	r := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	r = mux.SetURLVars(r, vars)
+	w := httptest.NewRecorder()
+	handler := l.apiListsHandler
+	if vars != nil {
+		handler = l.apiNodeHandler
+	}
+	appHandler(handler).ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestApiAuth(t *testing.T) {
+	l := newApiTestBoard(t, SiteConfig{})
+	read, _ := addTestToken(t, l, "!reader", scopeRead)
+	revoked, revokedId := addTestToken(t, l, "!revoked", scopeRead)
+	l.m.deleteApiToken(1, revokedId, Credentials{Tripcode: "!revoked"})
+	listId, _ := l.m.addNode(&Node{DomainId: 1, Title: "list", Tripcode: "!reader", Status: statusEnabled, Level: levelRoot})
+	node := map[string]string{"nodeId": strconv.Itoa(listId)}
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		vars   map[string]string
+		want   int
+	}{
+		{"missing token", "GET", "", nil, http.StatusUnauthorized},
+		{"unknown token", "GET", "unknown", nil, http.StatusUnauthorized},
+		{"revoked token", "GET", revoked, nil, http.StatusUnauthorized},
+		{"bearer token", "GET", read, nil, http.StatusOK},
+		{"read requests are limited", "GET", read, nil, http.StatusTooManyRequests},
+		{"missing scope", "DELETE", read, node, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apiRequest(l, tt.method, "/api/lists", tt.token, tt.vars); got != tt.want {
+				t.Errorf("%s = %d, want %d", tt.method, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApiNodeHandler(t *testing.T) {
+	l := newApiTestBoard(t, SiteConfig{Moderators: []Moderator{{Tripcode: "!moderator"}}})
+	owner, _ := addTestToken(t, l, "!owner", scopeModerate)
+	other, _ := addTestToken(t, l, "!other", scopeModerate)
+	moderator, _ := addTestToken(t, l, "!moderator", scopeModerate)
+	listId, _ := l.m.addNode(&Node{DomainId: 1, Title: "list", Tripcode: "!owner", Status: statusEnabled, Level: levelRoot})
+	itemId, _ := l.m.addNode(&Node{DomainId: 1, ParentId: listId, Title: "item", Tripcode: "!poster", Status: statusEnabled, Level: levelList})
+	del := func(token string, id int) int {
+		return apiRequest(l, "DELETE", "/api/node/"+strconv.Itoa(id), token, map[string]string{"nodeId": strconv.Itoa(id)})
+	}
+
+	if got := del(other, itemId); got != http.StatusForbidden {
+		t.Errorf("deleting a foreign item = %d, want %d", got, http.StatusForbidden)
+	}
+	if got := del(owner, itemId); got != http.StatusNoContent {
+		t.Errorf("deleting an item of an own list = %d, want %d", got, http.StatusNoContent)
+	}
+	if got := del(moderator, listId); got != http.StatusNoContent {
+		t.Errorf("deleting a list as a site moderator = %d, want %d", got, http.StatusNoContent)
+	}
+	if _, err := l.m.getNode(1, listId); err != sql.ErrNoRows {
+		t.Errorf("getNode() of the deleted list error = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestTokensHandlerHidesPassword(t *testing.T) {
+	l := newApiTestBoard(t, SiteConfig{})
+	password := "secret password"
+	addTestToken(t, l, l.config.tripcode(password), scopeRead)
+	form := url.Values{"action": {"list"}, "password": {password}}
+	r := httptest.NewRequest("POST", "/tokens.html", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	if err := l.tokensHandler(w, r); err != nil {
+		t.Fatal(err)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "Revoke") {
+		t.Fatal("tokensHandler() didn't list the token")
+	}
+	if strings.Contains(body, password) {
+		t.Error("tokensHandler() rendered the tripcode password")
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+)
+
+// doneItemsOrder puts the done items of a checklist after the open ones
+const doneItemsOrder = "done IS NOT NULL, "
+
+// feedSize is the number of entries in the feeds
+const feedSize = 20
+
+// IsChecklist tells if the items of the list are marked done
+func (n Node) IsChecklist() bool {
+	return n.Kind == kindChecklist
+}
+
+// IsDone tells if the checklist item is done
+func (n Node) IsDone() bool {
+	return n.Done != nil
+}
+
+// Progress counts the done items of a checklist
+type Progress struct {
+	ListId int `db:"parent_id"`
+	Done   int `db:"done"`
+	Total  int `db:"total"`
+}
+
+// Percent returns the share of the done items
+func (p Progress) Percent() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Done * 100 / p.Total
+}
+
+// ChecklistEvent records an item marked done or not done
+type ChecklistEvent struct {
+	Id        int       `db:"id"`
+	NodeId    int       `db:"node_id"`
+	ListId    int       `db:"list_id"`
+	DomainId  int       `db:"domain_id"`
+	Done      bool      `db:"done"`
+	Tripcode  string    `db:"tripcode"`
+	AccountId int       `db:"account_id"`
+	Username  string    `db:"username"`
+	Created   time.Time `db:"created"`
+	// Title is the title of the item
+	Title string `db:"title"`
+}
+
+// node shows the change like an item in the feeds
+func (e ChecklistEvent) node(ln *Language) Node {
+	state := ln.Lang("Done")
+	if !e.Done {
+		state = ln.Lang("Not done")
+	}
+	return Node{
+		Id:        e.NodeId,
+		ParentId:  e.ListId,
+		DomainId:  e.DomainId,
+		Title:     state + ": " + e.Title,
+		Tripcode:  e.Tripcode,
+		AccountId: e.AccountId,
+		Username:  e.Username,
+		Level:     levelList,
+		Created:   e.Created,
+	}
+}
+
+// getProgress returns the progress of the checklists among the lists by
+// their id. Checklists without items are left out.
+func (m *Model) getProgress(domainId int, lists *NodeList) (map[int]*Progress, error) {
+	progress := make(map[int]*Progress)
+	var ids []int
+	for _, list := range *lists {
+		if list.IsChecklist() {
+			ids = append(ids, list.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return progress, nil
+	}
+	query, args, err := sqlx.In("SELECT parent_id, COUNT(done) AS done, COUNT(*) AS total FROM node WHERE domain_id = ? AND level = ? AND status = 1 AND parent_id IN (?) GROUP BY parent_id",
+		domainId, levelList, ids)
+	if err != nil {
+		return nil, err
+	}
+	var pl []Progress
+	if err := m.db.Select(&pl, m.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for i := range pl {
+		progress[pl[i].ListId] = &pl[i]
+	}
+	return progress, nil
+}
+
+func (m *Model) mustGetProgress(domainId int, lists *NodeList) map[int]*Progress {
+	progress, err := m.getProgress(domainId, lists)
+	if err != nil {
+		panic(err)
+	}
+	return progress
+}
+
+// setDone marks the checklist item done or not done and records the change.
+// It returns false when the item is already in that state.
+func (m *Model) setDone(item *Node, done bool, c Credentials) (bool, error) {
+	if item.IsDone() == done {
+		return false, nil
+	}
+	now := time.Now()
+	var doneAt *time.Time
+	if done {
+		doneAt = &now
+	}
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	queries := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE node SET done = $1, updated = $2 WHERE domain_id = $3 AND id = $4",
+			[]interface{}{doneAt, now, item.DomainId, item.Id}},
+		{"UPDATE node SET updated = $1 WHERE domain_id = $2 AND id = $3",
+			[]interface{}{now, item.DomainId, item.ParentId}},
+		{"INSERT INTO checklist_event (node_id, list_id, domain_id, done, tripcode, account_id, username, created) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			[]interface{}{item.Id, item.ParentId, item.DomainId, done, c.Tripcode, c.AccountId, c.Username, now}},
+	}
+	for _, q := range queries {
+		if _, err := tx.Exec(q.query, q.args...); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	item.Done = doneAt
+	return true, nil
+}
+
+// getChecklistEvents returns the newest changes of the items of the list
+func (m *Model) getChecklistEvents(domainId, listId, limit int) ([]ChecklistEvent, error) {
+	var events []ChecklistEvent
+	err := m.db.Select(&events, `SELECT checklist_event.*, node.title AS title FROM checklist_event
+		JOIN node ON node.id = checklist_event.node_id
+		WHERE checklist_event.domain_id = $1 AND checklist_event.list_id = $2 AND node.status = 1
+		ORDER BY checklist_event.id DESC
+		LIMIT $3`, domainId, listId, limit)
+	return events, err
+}
+
+// doneErrors checks that the credentials may mark the item done
+func (l *ListBoard) doneErrors(list *Node, c Credentials, ln *Language) ValidationErrors {
+	if !list.IsChecklist() {
+		return ValidationErrors{ln.Lang("Only items of checklists can be marked done")}
+	}
+	if errors := closedErrors(list, ln); len(errors) != 0 {
+		return errors
+	}
+	if !l.canEditList(list, c) {
+		return ValidationErrors{ln.Lang("Only the editors of the list can mark items done")}
+	}
+	return nil
+}
+
+// doneFormHandler marks a checklist item done or not done
+func (l *ListBoard) doneFormHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+
+	var errors ValidationErrors
+	itemId, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		return err
+	}
+	item, err := l.m.getNode(sc.DomainId, itemId)
+	if err != nil {
+		return err
+	}
+	if item.Level != levelList {
+		return HTTPError{Message: "Only items can be marked done", Code: http.StatusNotFound}
+	}
+	list, err := l.m.getNode(sc.DomainId, item.ParentId)
+	if err != nil {
+		return err
+	}
+
+	tr := l.tp.Get(sc.Language)
+	if r.Method == "POST" && !inHoneypot(r.FormValue("name")) {
+		c := l.credentials(r, sc.DomainId)
+		if errors = l.doneErrors(list, c, tr); len(errors) == 0 {
+			// The form posts the wanted state, so posting it twice is harmless
+			if _, err := l.m.setDone(item, r.FormValue("done") == "1", c); err != nil {
+				return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+			}
+			http.Redirect(w, r, getUrl("", *item), http.StatusFound)
+			return nil
+		}
+	}
+
+	title, done := tr.Lang("Mark done"), "1"
+	if item.IsDone() {
+		title, done = tr.Lang("Mark not done"), "0"
+	}
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Item", item)
+	s.Set("Done", done)
+	s.Set("Action", title)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath(getUrl("", *list), list.Title)
+	s.AddPath("", title)
+	s.Set("Subtitle", title)
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("close.html"))
+}
+
+// listFeedHandler returns the newest items of a list and the changes of the
+// checklist items
+func (l *ListBoard) listFeedHandler(w http.ResponseWriter, r *http.Request) error {
+	listId, err := strconv.Atoi(mux.Vars(r)["listId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	list, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	if list.Level != levelRoot {
+		return HTTPError{Message: "Only lists have feeds", Code: http.StatusNotFound}
+	}
+	nodes, err := l.m.getChildNodes(sc.DomainId, listId, feedSize, 0, "created DESC")
+	if err != nil {
+		return err
+	}
+	if list.IsChecklist() {
+		events, err := l.m.getChecklistEvents(sc.DomainId, listId, feedSize)
+		if err != nil {
+			return err
+		}
+		tr := l.tp.Get(sc.Language)
+		for _, e := range events {
+			*nodes = append(*nodes, e.node(tr))
+		}
+		sort.SliceStable(*nodes, func(i, j int) bool {
+			return (*nodes)[i].Created.After((*nodes)[j].Created)
+		})
+		if len(*nodes) > feedSize {
+			*nodes = (*nodes)[:feedSize]
+		}
+	}
+	return l.feed(w, sc, "http://"+r.Host, nodes)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestModelChecklist(t *testing.T) {
+	m := newTestModel(t)
+	owner := Credentials{Tripcode: "!owner"}
+	listId, _ := m.addNode(&Node{DomainId: 1, Title: "todo", Tripcode: owner.Tripcode, Kind: kindChecklist, Status: statusEnabled, Level: levelRoot})
+	var items []*Node
+	for _, title := range []string{"first", "second", "third"} {
+		id, _ := m.addNode(&Node{DomainId: 1, ParentId: listId, Title: title, Status: statusEnabled, Level: levelList})
+		item, _ := m.getNode(1, id)
+		items = append(items, item)
+	}
+
+	if changed, err := m.setDone(items[0], true, owner); err != nil || !changed {
+		t.Fatalf("setDone() = %v, %v, want true", changed, err)
+	}
+	if changed, _ := m.setDone(items[0], true, owner); changed {
+		t.Error("setDone() of a done item changed it")
+	}
+	m.setDone(items[1], true, owner)
+	m.setDone(items[1], false, owner)
+
+	t.Run("done items come last", func(t *testing.T) {
+		nl, err := m.getChildNodes(1, listId, 10, 0, doneItemsOrder+"id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last := (*nl)[len(*nl)-1]; last.Id != items[0].Id || !last.IsDone() {
+			t.Errorf("last item = %d, want the done item %d", last.Id, items[0].Id)
+		}
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		list, _ := m.getNode(1, listId)
+		progress, err := m.getProgress(1, &NodeList{*list, Node{Id: 100}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := progress[listId]; p == nil || p.Done != 1 || p.Total != 3 || p.Percent() != 33 {
+			t.Errorf("getProgress() = %+v, want 1 of 3 done", p)
+		}
+		if _, ok := progress[100]; ok {
+			t.Error("getProgress() returned progress for a plain list")
+		}
+	})
+
+	t.Run("events", func(t *testing.T) {
+		events, err := m.getChecklistEvents(1, listId, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 3 {
+			t.Fatalf("getChecklistEvents() = %d events, want 3", len(events))
+		}
+		if e := events[0]; e.NodeId != items[1].Id || e.Done || e.Title != "second" {
+			t.Errorf("newest event = %+v, want second marked not done", e)
+		}
+	})
+}
+
+func TestDoneFormHandler(t *testing.T) {
+	l := newApiTestBoard(t, SiteConfig{})
+	listId, _ := l.m.addNode(&Node{DomainId: 1, Title: "todo", Tripcode: l.config.tripcode("secret"), Kind: kindChecklist, Status: statusEnabled, Level: levelRoot})
+	itemId, _ := l.m.addNode(&Node{DomainId: 1, ParentId: listId, Title: "item", Status: statusEnabled, Level: levelList})
+	post := func(done string) bool {
+		form := url.Values{"password": {"secret"}, "done": {done}}
+		r := httptest.NewRequest("POST", "/done.html?id="+strconv.Itoa(itemId), strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if err := l.doneFormHandler(httptest.NewRecorder(), r); err != nil {
+			t.Fatal(err)
+		}
+		item, _ := l.m.getNode(1, itemId)
+		return item.IsDone()
+	}
+	// A repeated post keeps the item done instead of toggling it back
+	for i := 0; i < 2; i++ {
+		if !post("1") {
+			t.Fatalf("post %d of done=1 left the item not done", i+1)
+		}
+	}
+	if post("0") {
+		t.Error("post of done=0 left the item done")
+	}
+}
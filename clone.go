@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCloneItems is the most items copied to a cloned list
+const maxCloneItems = 1000
+
+// ListTemplate prefills the form of a new list
+type ListTemplate struct {
+	Name string `json:"name"`
+	// Title may hold {date}, {year}, {month} and {week}, replaced with the
+	// current date
+	Title   string   `json:"title"`
+	Body    string   `json:"body"`
+	Kind    string   `json:"kind"`
+	Ranking string   `json:"ranking"`
+	Tags    []string `json:"tags"`
+	// Items are the default items of a new list or the options of a new poll
+	Items    []string     `json:"items"`
+	Settings ListSettings `json:"settings"`
+}
+
+// expandTitle replaces the date placeholders of the title pattern
+func expandTitle(pattern string, now time.Time) string {
+	year, week := now.ISOWeek()
+	return strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{year}", strconv.Itoa(now.Year()),
+		"{month}", now.Format("01"),
+		"{week}", fmt.Sprintf("%d-W%02d", year, week),
+	).Replace(pattern)
+}
+
+// node returns the new list prefilled from the template. Polls get the
+// items as options.
+func (t ListTemplate) node(domainId int, now time.Time) Node {
+	ls := t.Settings
+	node := Node{
+		DomainId: domainId,
+		Title:    expandTitle(t.Title, now),
+		Body:     t.Body,
+		Kind:     validKind(t.Kind),
+		Ranking:  validRanking(t.Ranking),
+		Tags:     parseTags(strings.Join(t.Tags, ",")),
+		Level:    levelRoot,
+	}
+	if node.IsPoll() {
+		node.Options = t.Items
+	}
+	node.Settings = newListSettings(node.IsPoll() && ls.Multiple, ls.Items, ls.Voting, ls.CommentsOnly, ls.MaxItems).encode()
+	return node
+}
+
+// defaultItems returns the first items of the new list. Polls take their
+// options from the form, other lists the items of the template if any.
+func (t *ListTemplate) defaultItems(list *Node) []Node {
+	if list.IsPoll() {
+		return titledItems(list.Options)
+	}
+	if t == nil {
+		return nil
+	}
+	return titledItems(t.Items)
+}
+
+// listTemplate returns the list template of the site by name
+func (sc *SiteConfig) listTemplate(name string) *ListTemplate {
+	for i := range sc.ListTemplates {
+		if sc.ListTemplates[i].Name == name {
+			return &sc.ListTemplates[i]
+		}
+	}
+	return nil
+}
+
+// cloneItems returns copies of the items without their votes
+func cloneItems(items *NodeList) []Node {
+	copies := make([]Node, len(*items))
+	for i, item := range *items {
+		copies[i] = Node{
+			Title:    item.Title,
+			Body:     item.Body,
+			Rendered: item.Rendered,
+			Previews: item.Previews,
+		}
+	}
+	return copies
+}
+
+// cloneFormHandler creates a new list from an existing one and optionally
+// copies its items
+func (l *ListBoard) cloneFormHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+
+	var errors ValidationErrors
+	listId, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		return err
+	}
+	source, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	if source.Level != levelRoot {
+		return HTTPError{Message: "Only lists can be cloned", Code: http.StatusNotFound}
+	}
+	items, err := l.m.getChildNodes(sc.DomainId, listId, maxCloneItems, 0, "id")
+	if err != nil {
+		return err
+	}
+
+	tr := l.tp.Get(sc.Language)
+	var node Node
+	copyItems := true
+	if r.Method == "POST" && !inHoneypot(r.FormValue("name")) {
+		node, errors = l.validateForm(r, sc, 0, levelRoot, tr)
+		copyItems = r.FormValue("copy_items") != ""
+		if len(errors) == 0 {
+			// Poll options come from the form, the other items are copied
+			var copies []Node
+			if node.IsPoll() {
+				copies = titledItems(node.Options)
+			} else if copyItems {
+				copies = cloneItems(items)
+			}
+			id, err := l.m.addList(&node, copies)
+			if err != nil {
+				return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+			}
+			http.Redirect(w, r, "/list/"+strconv.Itoa(id)+"/"+hfSlug(node.Title), http.StatusFound)
+			return nil
+		}
+	} else {
+		node = Node{
+			DomainId: sc.DomainId,
+			Title:    source.Title,
+			Body:     source.Body,
+			Kind:     source.Kind,
+			Ranking:  source.Ranking,
+			Settings: source.Settings,
+			Level:    levelRoot,
+		}
+		if node.Tags, err = l.m.getTagNames(sc.DomainId, listId); err != nil {
+			return err
+		}
+		if source.IsPoll() {
+			for _, item := range *items {
+				node.Options = append(node.Options, item.Title)
+			}
+		}
+	}
+
+	title := tr.Lang("Clone list")
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Form", node)
+	s.Set("FormTitle", title)
+	s.Set("ShowRanking", true)
+	s.Set("Rankings", rankingOptions)
+	s.Set("ShowKind", true)
+	s.Set("ShowCopyItems", true)
+	s.Set("CopyItems", copyItems)
+	s.Set("Action", title)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath(getUrl("", *source), source.Title)
+	s.AddPath("", title)
+	s.Set("Subtitle", title)
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("add.html"), sc.templatePath("form.html"))
+}
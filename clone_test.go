@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpandTitle(t *testing.T) {
+	now := time.Date(2024, time.January, 3, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"Sprint {week}", "Sprint 2024-W01"},
+		{"Standup {date}", "Standup 2024-01-03"},
+		{"Goals {year}/{month}", "Goals 2024/01"},
+		{"No placeholders", "No placeholders"},
+	}
+	for _, tt := range tests {
+		if got := expandTitle(tt.pattern, now); got != tt.want {
+			t.Errorf("expandTitle(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestListTemplateNode(t *testing.T) {
+	tmpl := ListTemplate{
+		Title:    "Retro {date}",
+		Kind:     "unknown",
+		Tags:     []string{"Team", "team"},
+		Items:    []string{"Went well", "To improve"},
+		Settings: ListSettings{Multiple: true, Items: itemsOwner, MaxItems: -1},
+	}
+	node := tmpl.node(1, time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC))
+	if node.Title != "Retro 2024-05-06" || node.Kind != kindList || len(node.Tags) != 1 {
+		t.Errorf("node() = %+v", node)
+	}
+	if len(node.Options) != 0 {
+		t.Errorf("node() options = %v, want none for a plain list", node.Options)
+	}
+	if got := node.ListSettings(); got != (ListSettings{Items: itemsOwner}) {
+		t.Errorf("node() settings = %+v, want only the items setting", got)
+	}
+	tmpl.Kind = kindPoll
+	if items := tmpl.defaultItems(&node); len(items) != 2 || items[0].Title != "Went well" {
+		t.Errorf("defaultItems() = %+v, want the items of the template", items)
+	}
+	tmpl.Kind = kindPoll
+	if node := tmpl.node(1, time.Now()); len(node.Options) != 2 || !node.ListSettings().Multiple {
+		t.Errorf("node() of a poll = %+v, want the items as options", node)
+	}
+	poll := Node{Kind: kindPoll, Options: []string{"A"}}
+	if items := tmpl.defaultItems(&poll); len(items) != 1 || items[0].Title != "A" {
+		t.Errorf("defaultItems() of a poll = %+v, want the options from the form", items)
+	}
+}
+
+func TestModelCloneItems(t *testing.T) {
+	m := newTestModel(t)
+	sourceId, _ := m.addNode(&Node{DomainId: 1, Title: "source", Status: statusEnabled, Level: levelRoot})
+	itemId, _ := m.addNode(&Node{DomainId: 1, ParentId: sourceId, Title: "item", Body: "item body", Tripcode: "!author", Status: statusEnabled, Level: levelList})
+	voteId, _ := m.addNode(&Node{DomainId: 1, ParentId: itemId, Title: "vote", Vote: 1, Status: statusEnabled, Level: levelVote})
+	m.Vote(1, 1, voteId, itemId, sourceId)
+
+	list := &Node{DomainId: 1, Title: "clone", Tripcode: "!cloner", Status: statusEnabled, Level: levelRoot}
+	items, _ := m.getChildNodes(1, sourceId, maxCloneItems, 0, "id")
+	if _, err := m.addList(list, cloneItems(items)); err != nil {
+		t.Fatal(err)
+	}
+	cloned, _ := m.getChildNodes(1, list.Id, maxCloneItems, 0, "id")
+	if len(*cloned) != 1 {
+		t.Fatalf("cloneItems() copied %d items, want 1", len(*cloned))
+	}
+	if item := (*cloned)[0]; item.Title != "item" || item.Body != "item body" || item.Vote != 0 || item.Tripcode != "!cloner" {
+		t.Errorf("cloned item = %+v, want the title and body without votes", item)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// closesFormat is the format of datetime-local inputs
+const closesFormat = "2006-01-02T15:04"
+
+// IsClosed tells if the list stopped accepting new items and votes
+func (n Node) IsClosed() bool {
+	return n.Closed || (n.Closes != nil && !n.Closes.After(time.Now()))
+}
+
+// ClosesInput returns the closing time in the format of the form input
+func (n Node) ClosesInput() string {
+	if n.Closes == nil {
+		return ""
+	}
+	return n.Closes.Local().Format(closesFormat)
+}
+
+// parseLocalTime reads an optional time from a datetime-local input or in
+// RFC 3339
+func parseLocalTime(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation(closesFormat, value, time.Local)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+// parseCloses reads the optional closing time of a list. A time in the past
+// closes the list right away.
+func parseCloses(value string, ln *Language) (*time.Time, ValidationErrors) {
+	closes, err := parseLocalTime(value)
+	if err != nil {
+		return nil, ValidationErrors{ln.Lang("Invalid closing time")}
+	}
+	return closes, nil
+}
+
+// closedErrors rejects changes to closed lists
+func closedErrors(list *Node, ln *Language) ValidationErrors {
+	if list.IsClosed() {
+		return ValidationErrors{ln.Lang("This list is closed")}
+	}
+	return nil
+}
+
+// deleteErrors rejects deleting the votes of closed lists as that would
+// change their final results
+func (l *ListBoard) deleteErrors(node *Node, ln *Language) (ValidationErrors, error) {
+	if node.Level != levelVote {
+		return nil, nil
+	}
+	item, err := l.m.getNode(node.DomainId, node.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	list, err := l.m.getNode(node.DomainId, item.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	return closedErrors(list, ln), nil
+}
+
+// setClosed closes or reopens a list owned or co-edited by the credentials. Reopening
+// clears a closing time that has already passed.
+func (m *Model) setClosed(list *Node, closed bool, c Credentials) (bool, error) {
+	closes := list.Closes
+	if !closed && closes != nil && !closes.After(time.Now()) {
+		closes = nil
+	}
+	res, err := m.db.NamedExec(`UPDATE node SET
+			closed = :closed,
+			closes = :closes,
+			updated = :updated
+			WHERE id = :id
+			AND domain_id = :domain_id
+			AND level = :level
+			`+listEditorCondition,
+		c.setParams(map[string]interface{}{
+			"closed":    closed,
+			"closes":    closes,
+			"updated":   time.Now(),
+			"id":        list.Id,
+			"domain_id": list.DomainId,
+			"level":     levelRoot,
+		}))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (l *ListBoard) closeFormHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+
+	var errors ValidationErrors
+	nodeId, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		return err
+	}
+	list, err := l.m.getNode(sc.DomainId, nodeId)
+	if err != nil {
+		return err
+	}
+	if list.Level != levelRoot {
+		return HTTPError{Message: "Only lists can be closed", Code: http.StatusNotFound}
+	}
+
+	tr := l.tp.Get(sc.Language)
+	if r.Method == "POST" {
+		if !inHoneypot(r.FormValue("name")) {
+			changed, err := l.m.setClosed(list, !list.IsClosed(), l.credentials(r, sc.DomainId))
+			if err != nil {
+				return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+			}
+			if changed {
+				http.Redirect(w, r, getUrl("http://"+r.Host, *list), http.StatusFound)
+				return nil
+			}
+			errors = append(errors, tr.Lang("Wrong tripcode password"))
+		}
+	}
+
+	title := tr.Lang("Close list")
+	if list.IsClosed() {
+		title = tr.Lang("Reopen list")
+	}
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Item", list)
+	s.Set("Action", title)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath("", title)
+	s.Set("Subtitle", title)
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("close.html"))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNodeIsClosed(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name string
+		node Node
+		want bool
+	}{
+		{"open", Node{}, false},
+		{"closed", Node{Closed: true}, true},
+		{"before the deadline", Node{Closes: &future}, false},
+		{"after the deadline", Node{Closes: &past}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.IsClosed(); got != tt.want {
+				t.Errorf("IsClosed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCloses(t *testing.T) {
+	ln := &Language{}
+	if closes, errors := parseCloses("", ln); closes != nil || len(errors) != 0 {
+		t.Errorf("parseCloses() = %v, %v, want no closing time", closes, errors)
+	}
+	closes, errors := parseCloses("2030-01-02T10:30", ln)
+	if len(errors) != 0 || closes.Format(closesFormat) != "2030-01-02T10:30" {
+		t.Errorf("parseCloses() = %v, %v", closes, errors)
+	}
+	if _, errors := parseCloses("tomorrow", ln); len(errors) == 0 {
+		t.Error("parseCloses() expected an error for an invalid time")
+	}
+}
+
+func TestModelSetClosed(t *testing.T) {
+	m := newTestModel(t)
+	past := time.Now().Add(-time.Minute)
+	id, _ := m.addNode(&Node{DomainId: 1, Title: "List", Tripcode: "abc", Closes: &past, Status: statusEnabled, Level: levelRoot})
+	list, _ := m.getNode(1, id)
+	if changed, _ := m.setClosed(list, false, Credentials{Tripcode: "xyz"}); changed {
+		t.Error("setClosed() changed a list of another author")
+	}
+	changed, err := m.setClosed(list, false, Credentials{Tripcode: "abc"})
+	if err != nil || !changed {
+		t.Fatalf("setClosed() = %v, %v", changed, err)
+	}
+	list, _ = m.getNode(1, id)
+	if list.IsClosed() || list.Closes != nil {
+		t.Errorf("reopened list closed = %v, closes = %v", list.IsClosed(), list.Closes)
+	}
+	m.setClosed(list, true, Credentials{Tripcode: "abc"})
+	if list, _ = m.getNode(1, id); !list.IsClosed() {
+		t.Error("setClosed() didn't close the list")
+	}
+}
+
+func TestDeleteErrors(t *testing.T) {
+	l := &ListBoard{m: newTestModel(t)}
+	ln := &Language{}
+	listId, _ := l.m.addNode(&Node{DomainId: 1, Title: "List", Tripcode: "abc", Status: statusEnabled, Level: levelRoot})
+	itemId, _ := l.m.addNode(&Node{DomainId: 1, ParentId: listId, Title: "Item", Status: statusEnabled, Level: levelList})
+	voteId, _ := l.m.addNode(&Node{DomainId: 1, ParentId: itemId, Title: "Vote", Vote: 1, Status: statusEnabled, Level: levelVote})
+	item, _ := l.m.getNode(1, itemId)
+	vote, _ := l.m.getNode(1, voteId)
+	if errors, err := l.deleteErrors(vote, ln); err != nil || len(errors) != 0 {
+		t.Errorf("deleteErrors() of an open list = %v, %v", errors, err)
+	}
+	list, _ := l.m.getNode(1, listId)
+	l.m.setClosed(list, true, Credentials{Tripcode: "abc"})
+	if errors, _ := l.deleteErrors(vote, ln); len(errors) != 1 {
+		t.Errorf("deleteErrors() of a vote in a closed list = %v, want 1 error", errors)
+	}
+	if errors, _ := l.deleteErrors(item, ln); len(errors) != 0 {
+		t.Errorf("deleteErrors() of an item = %v, want none", errors)
+	}
+}
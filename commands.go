@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+const commandBatchSize = 100
+
+// runCommand runs a maintenance command instead of starting the server
+func (l *ListBoard) runCommand(name string) error {
+	switch name {
+	case "rerender":
+		return l.rerender()
+	case "rerank":
+		return l.rerank()
+	case "recount":
+		return l.recount()
+	default:
+		return fmt.Errorf("unknown command %s", name)
+	}
+}
+
+// eachDomain calls fn once for every configured domain
+func (l *ListBoard) eachDomain(fn func(sc *SiteConfig) error) error {
+	done := make(map[int]bool)
+	for _, sc := range l.config.Servers {
+		if done[sc.DomainId] {
+			continue
+		}
+		done[sc.DomainId] = true
+		if err := fn(&sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachNode calls fn for every stored node of every configured domain
+func (l *ListBoard) eachNode(action string, fn func(sc *SiteConfig, node *Node) error) error {
+	return l.eachDomain(func(sc *SiteConfig) error {
+		count := 0
+		for offset := 0; ; offset += commandBatchSize {
+			nodes, err := l.m.getDomainNodes(sc.DomainId, commandBatchSize, offset)
+			if err != nil {
+				return err
+			}
+			for i := range *nodes {
+				if err := fn(sc, &(*nodes)[i]); err != nil {
+					return err
+				}
+			}
+			count += len(*nodes)
+			if len(*nodes) < commandBatchSize {
+				break
+			}
+		}
+		log.Printf("%s %d nodes of domain %d", action, count, sc.DomainId)
+		return nil
+	})
+}
+
+// rerender regenerates the rendered HTML and the references of every stored
+// node using the current markdown pipeline of its site
+func (l *ListBoard) rerender() error {
+	return l.eachNode("Rerendered", func(sc *SiteConfig, node *Node) error {
+		if err := l.renderNode(sc, node); err != nil {
+			return err
+		}
+		if err := l.m.setRendered(sc.DomainId, node.Id, node.Rendered, node.Previews); err != nil {
+			return err
+		}
+		return l.m.setReferences(node.Id, node.References)
+	})
+}
+
+// rerank recalculates the stored ranking scores of every node
+func (l *ListBoard) rerank() error {
+	return l.eachNode("Reranked", func(sc *SiteConfig, node *Node) error {
+		return l.m.updateRanks(sc.DomainId, node.Id)
+	})
+}
+
+// recount recalculates the vote counters from the stored vote nodes and
+// updates the ranking scores
+func (l *ListBoard) recount() error {
+	err := l.eachDomain(func(sc *SiteConfig) error {
+		log.Printf("Recounting the votes of domain %d", sc.DomainId)
+		return l.m.recountVotes(sc.DomainId)
+	})
+	if err != nil {
+		return err
+	}
+	return l.rerank()
+}
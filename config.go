@@ -4,19 +4,33 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+	"strings"
 )
 
 const defaultConfigFile = "./config/listboard.json"
 const defaultTemplatesBase = "./templates/default/"
+const defaultApiPostBlockExpire = "1s"
+const defaultApiReadBlockExpire = "100ms"
 
 type Config struct {
-	Server          string                `json:"server"`
-	Database        string                `json:"database"`
-	Dsn             string                `json:"dsn"`
-	Translations    string                `json:"translations"`
-	Token           string                `json:"token"`
-	PostBlockExpire string                `json:"post_block_expire"`
-	Servers         map[string]SiteConfig `json:"servers"`
+	Server             string                `json:"server"`
+	Database           string                `json:"database"`
+	Dsn                string                `json:"dsn"`
+	Translations       string                `json:"translations"`
+	Token              string                `json:"token"`
+	PostBlockExpire    string                `json:"post_block_expire"`
+	ApiPostBlockExpire string                `json:"api_post_block_expire"`
+	ApiReadBlockExpire string                `json:"api_read_block_expire"`
+	TripcodeSecret     string                `json:"tripcode_secret"`
+	Uploads            UploadConfig          `json:"uploads"`
+	Servers            map[string]SiteConfig `json:"servers"`
+}
+
+// Moderator names either an account or a tripcode, each is matched only
+// against credentials of its own kind
+type Moderator struct {
+	Username string `json:"username"`
+	Tripcode string `json:"tripcode"`
 }
 
 type SiteConfig struct {
@@ -32,10 +46,24 @@ type SiteConfig struct {
 	PostHeader  string `json:"post_header"`
 	PreFooter   string `json:"pre_footer"`
 	Templates   string `json:"templates"`
+	Ranking     string `json:"ranking"`
+	// Moderators are the accounts and tripcodes allowed to move and merge
+	// the items of any list
+	Moderators []Moderator `json:"moderators"`
+	// ListTemplates prefill the form of new lists
+	ListTemplates []ListTemplate `json:"list_templates"`
+
+	Markdown MarkdownConfig `json:"markdown"`
+	Previews PreviewConfig  `json:"previews"`
+
+	renderer Renderer
 }
 
 func NewConfig() *Config {
-	return &Config{}
+	return &Config{
+		ApiPostBlockExpire: defaultApiPostBlockExpire,
+		ApiReadBlockExpire: defaultApiReadBlockExpire,
+	}
 }
 
 func (c *Config) Load(args []string) error {
@@ -52,6 +80,11 @@ func (c *Config) Load(args []string) error {
 	if err := decoder.Decode(c); err != nil {
 		return err
 	}
+	c.Uploads.setDefaults()
+	for token, sc := range c.Servers {
+		sc.renderer = NewMarkdownRenderer(sc.Markdown)
+		c.Servers[token] = sc
+	}
 	return nil
 }
 
@@ -69,6 +102,37 @@ func (c *Config) getSiteConfig(token string) *SiteConfig {
 	return &sc
 }
 
+// tripcode returns the tripcode used for new posts. Secure tripcodes are
+// used when a tripcode secret is configured.
+func (c *Config) tripcode(password string) string {
+	if c.TripcodeSecret != "" {
+		return getSecureTripcode(c.TripcodeSecret, password)
+	}
+	return getTripcode(password)
+}
+
+// renderText renders the markdown text with the pipeline of the site
+func (sc *SiteConfig) renderText(t string) string {
+	if sc.renderer == nil {
+		sc.renderer = NewMarkdownRenderer(sc.Markdown)
+	}
+	return sc.renderer.Render(t)
+}
+
+// isModerator tells if the credentials belong to a site moderator
+func (sc *SiteConfig) isModerator(c Credentials) bool {
+	for _, m := range sc.Moderators {
+		// Usernames are unique regardless of their case
+		if m.Username != "" && c.AccountId != 0 && strings.EqualFold(m.Username, c.Username) {
+			return true
+		}
+		if m.Tripcode != "" && (m.Tripcode == c.Tripcode || m.Tripcode == c.ClassicTripcode) {
+			return true
+		}
+	}
+	return false
+}
+
 func (sc *SiteConfig) templatePath(templateName string) string {
 	if sc.Templates != "" {
 		return sc.Templates + templateName
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+const (
+	csrfCookie = "listboard_csrf"
+	csrfField  = "csrf"
+)
+
+// csrfToken returns the CSRF token of the visitor and sets the cookie holding
+// it when the visitor doesn't have one yet
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(csrfCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	token, _, err := newToken()
+	if err != nil {
+		panic(err)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+// validCsrf checks that the posted token matches the one in the cookie
+func validCsrf(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.FormValue(csrfField))) == 1
+}
+
+// csrf rejects POST requests without a valid CSRF token. The API routes
+// authenticate with bearer tokens and are not wrapped.
+func (l *ListBoard) csrf(fn appHandler) appHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method == "POST" && !validCsrf(r) {
+			sc := l.config.getSiteConfig(l.getToken(r))
+			return HTTPError{
+				Message: l.tp.Get(sc.Language).Lang("The form has expired, please reload the page and try again"),
+				Code:    http.StatusForbidden,
+			}
+		}
+		return fn(w, r)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newCsrfRequest(cookie, field string) *http.Request {
+	form := url.Values{}
+	form.Set(csrfField, field)
+	r := httptest.NewRequest("POST", "/add.html", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != "" {
+		r.AddCookie(&http.Cookie{Name: csrfCookie, Value: cookie})
+	}
+	return r
+}
+
+func TestValidCsrf(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string
+		field  string
+		want   bool
+	}{
+		{"accepts matching token", "token", "token", true},
+		{"rejects missing cookie", "", "token", false},
+		{"rejects missing field", "token", "", false},
+		{"rejects different token", "token", "other", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validCsrf(newCsrfRequest(tt.cookie, tt.field)); got != tt.want {
+				t.Errorf("validCsrf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCsrfToken(t *testing.T) {
+	t.Run("sets a cookie for new visitors", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		token := csrfToken(w, httptest.NewRequest("GET", "/", nil))
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Value != token {
+			t.Errorf("Expected the %s cookie to hold the token", csrfCookie)
+		}
+	})
+	t.Run("keeps the existing token", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: csrfCookie, Value: "token"})
+		if got := csrfToken(w, r); got != "token" {
+			t.Errorf("csrfToken() = %v, want token", got)
+		}
+		if len(w.Result().Cookies()) != 0 {
+			t.Errorf("Expected no new cookie")
+		}
+	})
+}
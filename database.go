@@ -14,22 +14,98 @@ type Model struct {
 }
 
 type Node struct {
-	Id       int       `db:"id"`
-	ParentId int       `db:"parent_id"`
-	DomainId int       `db:"domain_id"`
-	Title    string    `db:"title"`
-	Vote     int       `db:"vote"`
-	Tripcode string    `db:"tripcode"`
-	Body     string    `db:"body"`
-	Rendered string    `db:"rendered"`
-	Status   int       `db:"status"`
-	Level    int       `db:"level"`
-	Created  time.Time `db:"created"`
-	Updated  time.Time `db:"updated"`
+	Id        int       `db:"id" json:"id"`
+	ParentId  int       `db:"parent_id" json:"parent_id"`
+	DomainId  int       `db:"domain_id" json:"domain_id"`
+	Title     string    `db:"title" json:"title"`
+	Vote      int       `db:"vote" json:"vote"`
+	Up        int       `db:"up" json:"up"`
+	Down      int       `db:"down" json:"down"`
+	Comments  int       `db:"comments" json:"comments"`
+	Tripcode  string    `db:"tripcode" json:"tripcode"`
+	AccountId int       `db:"account_id" json:"account_id"`
+	Username  string    `db:"username" json:"username"`
+	Body      string    `db:"body" json:"body"`
+	Rendered  string    `db:"rendered" json:"rendered"`
+	Previews  string    `db:"previews" json:"-"`
+	Status    int       `db:"status" json:"status"`
+	Level     int       `db:"level" json:"level"`
+	Created   time.Time `db:"created" json:"created"`
+	Updated   time.Time `db:"updated" json:"updated"`
+
+	Wilson      float64 `db:"wilson" json:"-"`
+	Hot         float64 `db:"hot" json:"-"`
+	Controversy float64 `db:"controversy" json:"-"`
+	Ranking     string  `db:"ranking" json:"ranking"`
+	Kind        string  `db:"kind" json:"kind"`
+	Settings    string  `db:"settings" json:"-"`
+
+	Closed bool       `db:"closed" json:"closed"`
+	Closes *time.Time `db:"closes" json:"closes"`
+
+	// Pinned and Featured are the positions of the list in the pinned and
+	// featured sections, 0 when it is not there
+	Pinned     int        `db:"pinned" json:"pinned"`
+	PinExpires *time.Time `db:"pin_expires" json:"pin_expires"`
+	Featured   int        `db:"featured" json:"featured"`
+
+	// Done is the time a checklist item was marked done
+	Done *time.Time `db:"done" json:"done"`
+
+	// References holds the ids of the nodes referenced in the body
+	References []int `db:"-" json:"-"`
+	// Options holds the options of a new poll
+	Options []string `db:"-" json:"-"`
+	// Tags holds the tags of lists when they are saved or sent by the API
+	Tags []string `db:"-" json:"tags,omitempty"`
 }
 
 type NodeList []Node
 
+// Credentials identify the author of a node either by tripcode or account
+type Credentials struct {
+	Tripcode        string
+	ClassicTripcode string
+	AccountId       int
+	Username        string
+}
+
+// authorMatch matches the rows of node or list_editor belonging to the
+// credentials
+const authorMatch = `(
+			(tripcode != '' AND (tripcode = :tripcode OR tripcode = :classic_tripcode))
+			OR (account_id != 0 AND account_id = :account_id)
+		)`
+
+// authorCondition matches the nodes owned by the credentials
+const authorCondition = `AND ` + authorMatch
+
+// listEditorCondition matches the lists owned or co-edited by the
+// credentials
+const listEditorCondition = `AND (` + authorMatch + `
+			OR (parent_id = 0 AND id IN (SELECT list_id FROM list_editor WHERE ` + authorMatch + `))
+		)`
+
+// moderatorCondition matches the nodes owned by the credentials and the
+// items of the lists they own or co-edit
+const moderatorCondition = `AND (` + authorMatch + `
+			OR parent_id IN (SELECT id FROM node WHERE parent_id = 0 AND ` + authorMatch + `)
+			OR parent_id IN (SELECT list_id FROM list_editor WHERE ` + authorMatch + `)
+		)`
+
+// owns tells if the node belongs to the credentials, like authorCondition
+func (c Credentials) owns(n *Node) bool {
+	return (n.Tripcode != "" && (n.Tripcode == c.Tripcode || n.Tripcode == c.ClassicTripcode)) ||
+		(n.AccountId != 0 && n.AccountId == c.AccountId)
+}
+
+func (c Credentials) setParams(params map[string]interface{}) map[string]interface{} {
+	params["tripcode"] = c.Tripcode
+	params["classic_tripcode"] = c.ClassicTripcode
+	params["account_id"] = c.AccountId
+	return params
+}
+
 func NewModel(c *Config) *Model {
 	return &Model{}
 }
@@ -38,6 +114,15 @@ func (n *Node) GetRendered() template.HTML {
 	return template.HTML(n.Rendered)
 }
 
+// HasAuthor is true when the node can be edited by its author
+func (n Node) HasAuthor() bool {
+	return n.Tripcode != "" || n.AccountId != 0
+}
+
+func (n *Node) IsDeleted() bool {
+	return n.Status == statusDeleted
+}
+
 func (n *Node) Url() string {
 	switch n.Level {
 	case 0:
@@ -65,12 +150,27 @@ func (m *Model) getChildNodes(domainId, parentNodeId, count, offset int, orderBy
 	return &nl, err
 }
 
+// getChildNodesWithDeleted also returns the nodes deleted by their authors so
+// they can be shown as placeholders
+func (m *Model) getChildNodesWithDeleted(domainId, parentNodeId, count, offset int, orderBy string) (*NodeList, error) {
+	var nl NodeList
+	err := m.db.Select(&nl, "SELECT * FROM node WHERE domain_id = ? AND status IN (1, 2) AND parent_id=? ORDER BY "+orderBy+" LIMIT ?, ?", domainId, parentNodeId, offset, count)
+	return &nl, err
+}
+
 func (m *Model) getAllNodes(domainId, count, offset int, orderBy string) (*NodeList, error) {
 	var nl NodeList
 	err := m.db.Select(&nl, "SELECT * FROM node WHERE domain_id = ? AND status=1 ORDER BY "+orderBy+" LIMIT ?, ?", domainId, offset, count)
 	return &nl, err
 }
 
+// getDomainNodes returns all nodes of the domain regardless of their status
+func (m *Model) getDomainNodes(domainId, count, offset int) (*NodeList, error) {
+	var nl NodeList
+	err := m.db.Select(&nl, "SELECT * FROM node WHERE domain_id = ? ORDER BY id LIMIT ?, ?", domainId, offset, count)
+	return &nl, err
+}
+
 func (m *Model) mustGetChildNodes(domainId, parentNodeId, count, offset int, orderBy string) *NodeList {
 	nl, err := m.getChildNodes(domainId, parentNodeId, count, offset, orderBy)
 	if err != nil {
@@ -79,6 +179,14 @@ func (m *Model) mustGetChildNodes(domainId, parentNodeId, count, offset int, ord
 	return nl
 }
 
+func (m *Model) mustGetChildNodesWithDeleted(domainId, parentNodeId, count, offset int, orderBy string) *NodeList {
+	nl, err := m.getChildNodesWithDeleted(domainId, parentNodeId, count, offset, orderBy)
+	if err != nil {
+		panic(err)
+	}
+	return nl
+}
+
 func (m *Model) mustGetAllNodes(domainId, count, offset int, orderBy string) *NodeList {
 	nl, err := m.getAllNodes(domainId, count, offset, orderBy)
 	if err != nil {
@@ -89,7 +197,7 @@ func (m *Model) mustGetAllNodes(domainId, count, offset int, orderBy string) *No
 
 func (m *Model) getTotal(domainId, parentNodeId int) (int, error) {
 	var total int
-	err := m.db.Get(&total, "SELECT count(*) FROM node WHERE domain_id=$1 AND parent_id=$1 AND status=1", domainId, parentNodeId)
+	err := m.db.Get(&total, "SELECT count(*) FROM node WHERE domain_id=$1 AND parent_id=$2 AND status=1", domainId, parentNodeId)
 	return total, err
 }
 
@@ -101,6 +209,21 @@ func (m *Model) mustGetTotal(domainId, parentNodeId int) int {
 	return total
 }
 
+// getTotalWithDeleted counts the child nodes paged by getChildNodesWithDeleted
+func (m *Model) getTotalWithDeleted(domainId, parentNodeId int) (int, error) {
+	var total int
+	err := m.db.Get(&total, "SELECT count(*) FROM node WHERE domain_id=$1 AND parent_id=$2 AND status IN (1, 2)", domainId, parentNodeId)
+	return total, err
+}
+
+func (m *Model) mustGetTotalWithDeleted(domainId, parentNodeId int) int {
+	total, err := m.getTotalWithDeleted(domainId, parentNodeId)
+	if err != nil {
+		panic(err)
+	}
+	return total
+}
+
 func (m *Model) getNode(domainId, listId int) (*Node, error) {
 	var node Node
 	err := m.db.Get(&node, "SELECT * FROM node WHERE id=$1 AND domain_id=$2 AND status=1", listId, domainId)
@@ -108,16 +231,76 @@ func (m *Model) getNode(domainId, listId int) (*Node, error) {
 }
 
 func (m *Model) addNode(node *Node) (int, error) {
-	res, err := m.db.NamedExec(`INSERT INTO node (
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	id, err := insertNode(tx, node)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// addList stores a new list and its first items in one transaction
+func (m *Model) addList(list *Node, items []Node) (int, error) {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	id, err := insertNode(tx, list)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	list.Id = id
+	if err := addItems(tx, list, items); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// addItems stores the items in the list in the transaction. The items belong
+// to the author of the list.
+func addItems(tx *sqlx.Tx, list *Node, items []Node) error {
+	for _, item := range items {
+		item.ParentId = list.Id
+		item.DomainId = list.DomainId
+		item.Tripcode = list.Tripcode
+		item.AccountId = list.AccountId
+		item.Username = list.Username
+		item.Status = statusEnabled
+		item.Level = levelList
+		if _, err := insertNode(tx, &item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertNode stores the node with its tags and references in the transaction
+func insertNode(tx *sqlx.Tx, node *Node) (int, error) {
+	now := time.Now()
+	res, err := tx.NamedExec(`INSERT INTO node (
 			parent_id,
 			domain_id,
 			title,
 			vote,
 			tripcode,
+			account_id,
+			username,
 			body,
 			rendered,
+			previews,
 			status,
 			level,
+			hot,
+			ranking,
+			kind,
+			settings,
+			closes,
 			created,
 			updated
 		) VALUES (
@@ -126,36 +309,65 @@ func (m *Model) addNode(node *Node) (int, error) {
 			:title,
 			:vote,
 			:tripcode,
+			:account_id,
+			:username,
 			:body,
 			:rendered,
+			:previews,
 			:status,
 			:level,
+			:hot,
+			:ranking,
+			:kind,
+			:settings,
+			:closes,
 			:created,
 			:updated
   		)`,
 		map[string]interface{}{
-			"parent_id": node.ParentId,
-			"domain_id": node.DomainId,
-			"title":     node.Title,
-			"vote":      node.Vote,
-			"tripcode":  node.Tripcode,
-			"body":      node.Body,
-			"rendered":  string(node.Rendered),
-			"status":    node.Status,
-			"level":     node.Level,
-			"created":   time.Now(),
-			"updated":   time.Now(),
+			"parent_id":  node.ParentId,
+			"domain_id":  node.DomainId,
+			"title":      node.Title,
+			"vote":       node.Vote,
+			"tripcode":   node.Tripcode,
+			"account_id": node.AccountId,
+			"username":   node.Username,
+			"body":       node.Body,
+			"rendered":   string(node.Rendered),
+			"previews":   node.Previews,
+			"status":     node.Status,
+			"level":      node.Level,
+			"hot":        hotScore(0, 0, now),
+			"ranking":    node.Ranking,
+			"kind":       node.Kind,
+			"settings":   node.Settings,
+			"closes":     node.Closes,
+			"created":    now,
+			"updated":    now,
 		})
 	if err != nil {
 		return 0, err
 	}
 	var id int64
-	id, err = res.LastInsertId()
-	return int(id), err
+	if id, err = res.LastInsertId(); err != nil {
+		return 0, err
+	}
+	if node.Level == levelRoot && len(node.Tags) > 0 {
+		if err := replaceTags(tx, node.DomainId, int(id), node.Tags); err != nil {
+			return 0, err
+		}
+	}
+	return int(id), replaceReferences(tx, int(id), node.References)
 }
 
 func (m *Model) bumpVote(domainId, id, vote int) error {
-	_, err := m.db.NamedExec(`UPDATE node set vote = vote + :vote, updated = :updated WHERE domain_id = :domain_id AND id = :id`, map[string]interface{}{
+	return bumpVote(m.db, domainId, id, vote)
+}
+
+// bumpVote changes the vote total of the node in the database or in a
+// transaction
+func bumpVote(e sqlx.Ext, domainId, id, vote int) error {
+	_, err := sqlx.NamedExec(e, `UPDATE node set vote = vote + :vote, updated = :updated WHERE domain_id = :domain_id AND id = :id`, map[string]interface{}{
 		"vote":      vote,
 		"id":        id,
 		"updated":   time.Now(),
@@ -165,7 +377,7 @@ func (m *Model) bumpVote(domainId, id, vote int) error {
 }
 
 func (m *Model) Vote(domainId, vote, id, itemId, listId int) error {
-	if err := m.bumpVote(domainId, itemId, vote); err != nil {
+	if err := m.countVote(domainId, itemId, vote, 1); err != nil {
 		return err
 	}
 	// parent holds total number of votes
@@ -175,23 +387,125 @@ func (m *Model) Vote(domainId, vote, id, itemId, listId int) error {
 	return nil
 }
 
-func (m *Model) editNode(node *Node) error {
-	_, err := m.db.NamedExec(`UPDATE node SET
+func (m *Model) setRendered(domainId, id int, rendered, previews string) error {
+	_, err := m.db.Exec("UPDATE node SET rendered = $1, previews = $2 WHERE domain_id = $3 AND id = $4", rendered, previews, domainId, id)
+	return err
+}
+
+// Unvote reverts the counters updated by Vote
+func (m *Model) Unvote(domainId, vote, itemId, listId int) error {
+	return unvote(m.db, domainId, vote, itemId, listId)
+}
+
+// unvote reverts the counters in the database or in a transaction
+func unvote(e sqlx.Ext, domainId, vote, itemId, listId int) error {
+	if err := countVote(e, domainId, itemId, vote, -1); err != nil {
+		return err
+	}
+	return bumpVote(e, domainId, listId, -1)
+}
+
+// editNode updates the node if it belongs to the credentials. Lists may
+// also be edited by their co-editors.
+func (m *Model) editNode(node *Node, c Credentials) error {
+	res, err := m.db.NamedExec(`UPDATE node SET
 			title = :title,
 			body = :body,
 			rendered = :rendered,
+			previews = :previews,
+			ranking = :ranking,
+			closes = :closes,
+			settings = :settings,
 			updated = :updated
 			WHERE id = :id
 			AND domain_id = :domain_id
-			AND tripcode = :tripcode`,
-		map[string]interface{}{
+			`+listEditorCondition,
+		c.setParams(map[string]interface{}{
 			"title":     node.Title,
 			"body":      node.Body,
 			"rendered":  string(node.Rendered),
+			"previews":  node.Previews,
+			"ranking":   node.Ranking,
+			"closes":    node.Closes,
+			"settings":  node.Settings,
 			"updated":   time.Now(),
 			"id":        node.Id,
 			"domain_id": node.DomainId,
-			"tripcode":  node.Tripcode,
-		})
-	return err
+		}))
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return err
+	}
+	if node.Level == levelRoot {
+		if err := m.setTags(node.DomainId, node.Id, node.Tags); err != nil {
+			return err
+		}
+	}
+	return m.setReferences(node.Id, node.References)
+}
+
+// deleteNode marks the node as deleted if it belongs to the credentials or
+// is an item of a list they own or co-edit. Returns false if the node was
+// not deleted.
+func (m *Model) deleteNode(node *Node, c Credentials) (bool, error) {
+	return m.removeNode(node, moderatorCondition, c)
+}
+
+// deleteAnyNode marks the node as deleted for the site moderators
+func (m *Model) deleteAnyNode(node *Node) (bool, error) {
+	return m.removeNode(node, "", Credentials{})
+}
+
+// removeNode marks the node matching the condition as deleted and takes
+// back its vote
+func (m *Model) removeNode(node *Node, condition string, c Credentials) (bool, error) {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	deleted, err := removeNode(tx, node, condition, c)
+	if err != nil || !deleted {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// removeNode marks the node as deleted and takes back its vote in the
+// transaction
+func removeNode(tx *sqlx.Tx, node *Node, condition string, c Credentials) (bool, error) {
+	res, err := tx.NamedExec(`UPDATE node SET
+			status = :status,
+			updated = :updated
+			WHERE id = :id
+			AND domain_id = :domain_id
+			AND status = :enabled
+			`+condition,
+		c.setParams(map[string]interface{}{
+			"status":    statusDeleted,
+			"updated":   time.Now(),
+			"id":        node.Id,
+			"domain_id": node.DomainId,
+			"enabled":   statusEnabled,
+		}))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	if node.Level == levelVote {
+		// The item may have been deleted already, its counters still change
+		var listId int
+		if err := tx.Get(&listId, "SELECT parent_id FROM node WHERE domain_id = $1 AND id = $2", node.DomainId, node.ParentId); err != nil {
+			return false, err
+		}
+		if err := unvote(tx, node.DomainId, node.Vote, node.ParentId, listId); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
 }
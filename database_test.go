@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+// newTestModel returns a model backed by an in-memory database
+func newTestModel(t *testing.T) *Model {
+	t.Helper()
+	m := NewModel(nil)
+	if err := m.Init(&Config{Database: "sqlite", Dsn: ":memory:"}); err != nil {
+		t.Fatal(err)
+	}
+	// Every connection gets its own in-memory database
+	m.db.SetMaxOpenConns(1)
+	schema, err := os.ReadFile("db/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.db.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.db.Close() })
+	return m
+}
+
+func TestModelNodes(t *testing.T) {
+	m := newTestModel(t)
+	id, err := m.addNode(&Node{DomainId: 1, Title: "List", Status: statusEnabled, Level: levelRoot})
+	if err != nil {
+		t.Fatalf("addNode() error = %v", err)
+	}
+	node, err := m.getNode(1, id)
+	if err != nil {
+		t.Fatalf("getNode() error = %v", err)
+	}
+	if node.Title != "List" {
+		t.Errorf("getNode() title = %v, want List", node.Title)
+	}
+}
+
+func TestModelDeleteNode(t *testing.T) {
+	m := newTestModel(t)
+	author := Credentials{Tripcode: "!author"}
+	listId, _ := m.addNode(&Node{DomainId: 1, Title: "List", Tripcode: "!owner", Status: statusEnabled, Level: levelRoot})
+	itemId, _ := m.addNode(&Node{DomainId: 1, ParentId: listId, Title: "Item", Status: statusEnabled, Level: levelList})
+	addVote := func(vote int) *Node {
+		id, _ := m.addNode(&Node{DomainId: 1, ParentId: itemId, Title: "Vote", Vote: vote, Tripcode: author.Tripcode, Status: statusEnabled, Level: levelVote})
+		if err := m.Vote(1, vote, id, itemId, listId); err != nil {
+			t.Fatal(err)
+		}
+		node, _ := m.getNode(1, id)
+		return node
+	}
+	up := addVote(1)
+	down := addVote(-1)
+	if item, _ := m.getNode(1, itemId); item.Up != 1 || item.Down != 1 {
+		t.Fatalf("Vote() counters = +%d -%d, want +1 -1", item.Up, item.Down)
+	}
+
+	t.Run("rejects a foreign tripcode", func(t *testing.T) {
+		deleted, err := m.deleteNode(up, Credentials{Tripcode: "!other"})
+		if err != nil || deleted {
+			t.Errorf("deleteNode() = %v, %v, want false", deleted, err)
+		}
+		if _, err := m.getNode(1, up.Id); err != nil {
+			t.Errorf("getNode() of the kept vote error = %v", err)
+		}
+	})
+
+	t.Run("deletes an own post and takes back its vote", func(t *testing.T) {
+		for _, vote := range []*Node{up, down} {
+			if deleted, err := m.deleteNode(vote, author); err != nil || !deleted {
+				t.Fatalf("deleteNode() = %v, %v, want true", deleted, err)
+			}
+		}
+		if _, err := m.getNode(1, up.Id); err != sql.ErrNoRows {
+			t.Errorf("getNode() of the deleted vote error = %v, want %v", err, sql.ErrNoRows)
+		}
+		item, _ := m.getNode(1, itemId)
+		list, _ := m.getNode(1, listId)
+		if item.Vote != 0 || item.Up != 0 || item.Down != 0 || list.Vote != 0 {
+			t.Errorf("counters = %d (+%d -%d), list %d, want all 0", item.Vote, item.Up, item.Down, list.Vote)
+		}
+	})
+
+	t.Run("takes back the vote of a deleted item", func(t *testing.T) {
+		vote := addVote(1)
+		item, _ := m.getNode(1, itemId)
+		if deleted, err := m.deleteNode(item, Credentials{Tripcode: "!owner"}); err != nil || !deleted {
+			t.Fatalf("deleteNode() of the item = %v, %v, want true", deleted, err)
+		}
+		if deleted, err := m.deleteNode(vote, author); err != nil || !deleted {
+			t.Fatalf("deleteNode() = %v, %v, want true", deleted, err)
+		}
+		if list, _ := m.getNode(1, listId); list.Vote != 0 {
+			t.Errorf("list vote = %d, want 0", list.Vote)
+		}
+	})
+
+	t.Run("deleting twice doesn't count again", func(t *testing.T) {
+		if deleted, _ := m.deleteNode(up, author); deleted {
+			t.Error("deleteNode() deleted a deleted vote")
+		}
+		if list, _ := m.getNode(1, listId); list.Vote != 0 {
+			t.Errorf("list vote = %d, want 0", list.Vote)
+		}
+	})
+}
+
+func TestModelGetTotalWithDeleted(t *testing.T) {
+	m := newTestModel(t)
+	listId, _ := m.addNode(&Node{DomainId: 1, Title: "List", Status: statusEnabled, Level: levelRoot})
+	for _, status := range []int{statusEnabled, statusDeleted, statusMerged} {
+		m.addNode(&Node{DomainId: 1, ParentId: listId, Title: "Item", Status: status, Level: levelList})
+	}
+	nodes, _ := m.getChildNodesWithDeleted(1, listId, 10, 0, "id")
+	if total, err := m.getTotalWithDeleted(1, listId); err != nil || total != len(*nodes) {
+		t.Errorf("getTotalWithDeleted() = %d, %v, want %d", total, err, len(*nodes))
+	}
+	if total, _ := m.getTotal(1, listId); total != 1 {
+		t.Errorf("getTotal() = %d, want 1", total)
+	}
+}
@@ -0,0 +1,23 @@
+-- Upgrades databases created before user accounts were added
+BEGIN TRANSACTION;
+ALTER TABLE node ADD COLUMN account_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE node ADD COLUMN username character varying(32) DEFAULT '';
+CREATE INDEX IF NOT EXISTS account_id_ndx ON node(account_id);
+
+CREATE TABLE IF NOT EXISTS account (
+    id INTEGER PRIMARY KEY NOT NULL,
+    domain_id smallint DEFAULT 0,
+    username character varying(32) NOT NULL,
+    password character varying(60) NOT NULL,
+    created timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS account_username_ndx ON account(domain_id, username COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS login (
+    token character varying(64) PRIMARY KEY NOT NULL,
+    account_id INTEGER NOT NULL,
+    expires timestamp
+);
+
+COMMIT TRANSACTION;
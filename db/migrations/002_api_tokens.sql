@@ -0,0 +1,16 @@
+-- Adds personal API tokens
+BEGIN TRANSACTION;
+CREATE TABLE IF NOT EXISTS api_token (
+    id INTEGER PRIMARY KEY NOT NULL,
+    domain_id smallint DEFAULT 0,
+    token character varying(64) NOT NULL,
+    name character varying(150) DEFAULT '',
+    scopes character varying(100) DEFAULT '',
+    account_id INTEGER NOT NULL DEFAULT 0,
+    tripcode character varying(12) DEFAULT '',
+    created timestamp,
+    used timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_token_ndx ON api_token(token);
+COMMIT TRANSACTION;
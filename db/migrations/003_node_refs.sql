@@ -0,0 +1,10 @@
+-- Adds the references between nodes, run the rerender command afterwards
+BEGIN TRANSACTION;
+CREATE TABLE IF NOT EXISTS node_ref (
+    node_id INTEGER NOT NULL,
+    ref_id INTEGER NOT NULL,
+    PRIMARY KEY (node_id, ref_id)
+);
+
+CREATE INDEX IF NOT EXISTS node_ref_ref_id_ndx ON node_ref(ref_id);
+COMMIT TRANSACTION;
@@ -0,0 +1,13 @@
+-- Adds the link previews stored with the nodes
+BEGIN TRANSACTION;
+ALTER TABLE node ADD COLUMN previews text DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS link_preview (
+    url character varying(2000) PRIMARY KEY NOT NULL,
+    title text DEFAULT '',
+    description text DEFAULT '',
+    image text DEFAULT '',
+    video_id character varying(32) DEFAULT '',
+    fetched timestamp
+);
+COMMIT TRANSACTION;
@@ -0,0 +1,14 @@
+-- Adds the up and down vote counters and the stored ranking scores
+BEGIN TRANSACTION;
+ALTER TABLE node ADD COLUMN up int NOT NULL DEFAULT 0;
+ALTER TABLE node ADD COLUMN down int NOT NULL DEFAULT 0;
+ALTER TABLE node ADD COLUMN wilson REAL NOT NULL DEFAULT 0;
+ALTER TABLE node ADD COLUMN hot REAL NOT NULL DEFAULT 0;
+ALTER TABLE node ADD COLUMN controversy REAL NOT NULL DEFAULT 0;
+ALTER TABLE node ADD COLUMN ranking character varying(16) DEFAULT '';
+
+UPDATE node SET
+    up = (SELECT COUNT(*) FROM node v WHERE v.parent_id = node.id AND v.level = 4 AND v.status = 1 AND v.vote > 0),
+    down = (SELECT COUNT(*) FROM node v WHERE v.parent_id = node.id AND v.level = 4 AND v.status = 1 AND v.vote < 0)
+    WHERE level = 3;
+COMMIT TRANSACTION;
@@ -0,0 +1,8 @@
+-- Adds the comment counter of the items
+BEGIN TRANSACTION;
+ALTER TABLE node ADD COLUMN comments int NOT NULL DEFAULT 0;
+
+UPDATE node SET
+    comments = (SELECT COUNT(*) FROM node v WHERE v.parent_id = node.id AND v.level = 4 AND v.status = 1 AND v.vote = 0)
+    WHERE level = 3;
+COMMIT TRANSACTION;
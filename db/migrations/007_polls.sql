@@ -0,0 +1,15 @@
+-- Adds the list kinds and settings and the votes of polls
+BEGIN TRANSACTION;
+ALTER TABLE node ADD COLUMN kind character varying(16) DEFAULT '';
+ALTER TABLE node ADD COLUMN settings text DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS poll_vote (
+    list_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    voter character varying(80) NOT NULL,
+    created timestamp,
+    PRIMARY KEY (list_id, voter, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS poll_vote_item_id_ndx ON poll_vote(item_id);
+COMMIT TRANSACTION;
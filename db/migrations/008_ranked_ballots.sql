@@ -0,0 +1,11 @@
+-- Adds the ballots of ranked choice lists
+BEGIN TRANSACTION;
+CREATE TABLE IF NOT EXISTS ranked_ballot (
+    list_id INTEGER NOT NULL,
+    voter character varying(80) NOT NULL,
+    item_id INTEGER NOT NULL,
+    position smallint NOT NULL,
+    created timestamp,
+    PRIMARY KEY (list_id, voter, item_id)
+);
+COMMIT TRANSACTION;
@@ -0,0 +1,5 @@
+-- Adds the closed state and the closing time of the lists
+BEGIN TRANSACTION;
+ALTER TABLE node ADD COLUMN closed boolean NOT NULL DEFAULT 0;
+ALTER TABLE node ADD COLUMN closes timestamp;
+COMMIT TRANSACTION;
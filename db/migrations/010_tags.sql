@@ -0,0 +1,12 @@
+-- Adds the tags of the lists
+BEGIN TRANSACTION;
+CREATE TABLE IF NOT EXISTS node_tag (
+    node_id INTEGER NOT NULL,
+    domain_id smallint DEFAULT 0,
+    tag character varying(32) NOT NULL,
+    slug character varying(32) NOT NULL,
+    PRIMARY KEY (node_id, slug)
+);
+
+CREATE INDEX IF NOT EXISTS node_tag_slug_ndx ON node_tag(domain_id, slug);
+COMMIT TRANSACTION;
@@ -0,0 +1,9 @@
+-- Adds the redirects of merged lists
+BEGIN TRANSACTION;
+CREATE TABLE IF NOT EXISTS node_redirect (
+    node_id INTEGER PRIMARY KEY,
+    domain_id smallint DEFAULT 0,
+    target_id INTEGER NOT NULL,
+    created timestamp
+);
+COMMIT TRANSACTION;
@@ -0,0 +1,6 @@
+-- Adds the pinned and featured positions of the lists
+BEGIN TRANSACTION;
+ALTER TABLE node ADD COLUMN pinned smallint NOT NULL DEFAULT 0;
+ALTER TABLE node ADD COLUMN pin_expires timestamp;
+ALTER TABLE node ADD COLUMN featured smallint NOT NULL DEFAULT 0;
+COMMIT TRANSACTION;
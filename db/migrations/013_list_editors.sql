@@ -0,0 +1,13 @@
+-- Adds the co-editors of the lists
+BEGIN TRANSACTION;
+CREATE TABLE IF NOT EXISTS list_editor (
+    id INTEGER PRIMARY KEY NOT NULL,
+    list_id INTEGER NOT NULL,
+    domain_id smallint DEFAULT 0,
+    tripcode character varying(12) DEFAULT '',
+    account_id INTEGER NOT NULL DEFAULT 0,
+    created timestamp
+);
+
+CREATE INDEX IF NOT EXISTS list_editor_list_id_ndx ON list_editor(list_id);
+COMMIT TRANSACTION;
@@ -0,0 +1,17 @@
+-- Adds the done state of the checklist items and its changes
+BEGIN TRANSACTION;
+ALTER TABLE node ADD COLUMN done timestamp;
+CREATE TABLE IF NOT EXISTS checklist_event (
+    id INTEGER PRIMARY KEY NOT NULL,
+    node_id INTEGER NOT NULL,
+    list_id INTEGER NOT NULL,
+    domain_id smallint DEFAULT 0,
+    done boolean NOT NULL DEFAULT 0,
+    tripcode character varying(12) DEFAULT '',
+    account_id INTEGER NOT NULL DEFAULT 0,
+    username character varying(32) DEFAULT '',
+    created timestamp
+);
+
+CREATE INDEX IF NOT EXISTS checklist_event_list_id_ndx ON checklist_event(list_id);
+COMMIT TRANSACTION;
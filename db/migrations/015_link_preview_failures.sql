@@ -0,0 +1,4 @@
+-- Caches the failed link previews so they are not fetched on every post
+BEGIN TRANSACTION;
+ALTER TABLE link_preview ADD COLUMN failed boolean NOT NULL DEFAULT 0;
+COMMIT TRANSACTION;
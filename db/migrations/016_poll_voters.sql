@@ -0,0 +1,11 @@
+-- Records each poll voter once so a voter can't vote twice at the same time
+BEGIN TRANSACTION;
+CREATE TABLE IF NOT EXISTS poll_voter (
+    list_id INTEGER NOT NULL,
+    voter character varying(80) NOT NULL,
+    created timestamp,
+    PRIMARY KEY (list_id, voter)
+);
+INSERT OR IGNORE INTO poll_voter (list_id, voter, created)
+    SELECT list_id, voter, MIN(created) FROM poll_vote GROUP BY list_id, voter;
+COMMIT TRANSACTION;
@@ -5,11 +5,29 @@ CREATE TABLE IF NOT EXISTS node (
     domain_id smallint DEFAULT 0,
     title character varying(150) DEFAULT '',
     vote int DEFAULT 0,
-    tripcode character varying(10) DEFAULT '',
+    up int NOT NULL DEFAULT 0,
+    down int NOT NULL DEFAULT 0,
+    comments int NOT NULL DEFAULT 0,
+    tripcode character varying(12) DEFAULT '',
+    account_id INTEGER NOT NULL DEFAULT 0,
+    username character varying(32) DEFAULT '',
     body text,
     rendered text,
+    previews text DEFAULT '',
     level smallint DEFAULT 0,
     status smallint DEFAULT 1,
+    wilson REAL NOT NULL DEFAULT 0,
+    hot REAL NOT NULL DEFAULT 0,
+    controversy REAL NOT NULL DEFAULT 0,
+    ranking character varying(16) DEFAULT '',
+    kind character varying(16) DEFAULT '',
+    settings text DEFAULT '',
+    closed boolean NOT NULL DEFAULT 0,
+    closes timestamp,
+    pinned smallint NOT NULL DEFAULT 0,
+    pin_expires timestamp,
+    featured smallint NOT NULL DEFAULT 0,
+    done timestamp,
     created timestamp,
     updated timestamp
 );
@@ -20,5 +38,122 @@ CREATE INDEX IF NOT EXISTS vote_ndx ON node(vote DESC);
 CREATE INDEX IF NOT EXISTS status_ndx ON node(status DESC);
 CREATE INDEX IF NOT EXISTS created_ndx ON node(created DESC);
 CREATE INDEX IF NOT EXISTS updated_ndx ON node(updated DESC);
+CREATE INDEX IF NOT EXISTS account_id_ndx ON node(account_id);
+
+CREATE TABLE IF NOT EXISTS account (
+    id INTEGER PRIMARY KEY NOT NULL,
+    domain_id smallint DEFAULT 0,
+    username character varying(32) NOT NULL,
+    password character varying(60) NOT NULL,
+    created timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS account_username_ndx ON account(domain_id, username COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS login (
+    token character varying(64) PRIMARY KEY NOT NULL,
+    account_id INTEGER NOT NULL,
+    expires timestamp
+);
+
+CREATE TABLE IF NOT EXISTS api_token (
+    id INTEGER PRIMARY KEY NOT NULL,
+    domain_id smallint DEFAULT 0,
+    token character varying(64) NOT NULL,
+    name character varying(150) DEFAULT '',
+    scopes character varying(100) DEFAULT '',
+    account_id INTEGER NOT NULL DEFAULT 0,
+    tripcode character varying(12) DEFAULT '',
+    created timestamp,
+    used timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_token_ndx ON api_token(token);
+
+CREATE TABLE IF NOT EXISTS node_ref (
+    node_id INTEGER NOT NULL,
+    ref_id INTEGER NOT NULL,
+    PRIMARY KEY (node_id, ref_id)
+);
+
+CREATE INDEX IF NOT EXISTS node_ref_ref_id_ndx ON node_ref(ref_id);
+
+CREATE TABLE IF NOT EXISTS link_preview (
+    url character varying(2000) PRIMARY KEY NOT NULL,
+    title text DEFAULT '',
+    description text DEFAULT '',
+    image text DEFAULT '',
+    video_id character varying(32) DEFAULT '',
+    fetched timestamp,
+    failed boolean NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS poll_vote (
+    list_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    voter character varying(80) NOT NULL,
+    created timestamp,
+    PRIMARY KEY (list_id, voter, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS poll_vote_item_id_ndx ON poll_vote(item_id);
+
+CREATE TABLE IF NOT EXISTS poll_voter (
+    list_id INTEGER NOT NULL,
+    voter character varying(80) NOT NULL,
+    created timestamp,
+    PRIMARY KEY (list_id, voter)
+);
+
+CREATE TABLE IF NOT EXISTS ranked_ballot (
+    list_id INTEGER NOT NULL,
+    voter character varying(80) NOT NULL,
+    item_id INTEGER NOT NULL,
+    position smallint NOT NULL,
+    created timestamp,
+    PRIMARY KEY (list_id, voter, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS node_tag (
+    node_id INTEGER NOT NULL,
+    domain_id smallint DEFAULT 0,
+    tag character varying(32) NOT NULL,
+    slug character varying(32) NOT NULL,
+    PRIMARY KEY (node_id, slug)
+);
+
+CREATE INDEX IF NOT EXISTS node_tag_slug_ndx ON node_tag(domain_id, slug);
+
+CREATE TABLE IF NOT EXISTS list_editor (
+    id INTEGER PRIMARY KEY NOT NULL,
+    list_id INTEGER NOT NULL,
+    domain_id smallint DEFAULT 0,
+    tripcode character varying(12) DEFAULT '',
+    account_id INTEGER NOT NULL DEFAULT 0,
+    created timestamp
+);
+
+CREATE INDEX IF NOT EXISTS list_editor_list_id_ndx ON list_editor(list_id);
+
+CREATE TABLE IF NOT EXISTS node_redirect (
+    node_id INTEGER PRIMARY KEY,
+    domain_id smallint DEFAULT 0,
+    target_id INTEGER NOT NULL,
+    created timestamp
+);
+
+CREATE TABLE IF NOT EXISTS checklist_event (
+    id INTEGER PRIMARY KEY NOT NULL,
+    node_id INTEGER NOT NULL,
+    list_id INTEGER NOT NULL,
+    domain_id smallint DEFAULT 0,
+    done boolean NOT NULL DEFAULT 0,
+    tripcode character varying(12) DEFAULT '',
+    account_id INTEGER NOT NULL DEFAULT 0,
+    username character varying(32) DEFAULT '',
+    created timestamp
+);
+
+CREATE INDEX IF NOT EXISTS checklist_event_list_id_ndx ON checklist_event(list_id);
 
 COMMIT TRANSACTION;
\ No newline at end of file
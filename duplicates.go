@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gosimple/slug"
+)
+
+const (
+	// duplicateThreshold is the lowest title similarity of a suggestion
+	duplicateThreshold = 0.7
+	// duplicateCandidates is the number of newest siblings compared
+	duplicateCandidates = 1000
+	// duplicateSuggestions is the number of suggestions shown
+	duplicateSuggestions = 5
+)
+
+// normalizeTitle lowercases and transliterates the title and drops the
+// punctuation, the same way slugs are made
+func normalizeTitle(title string) string {
+	return strings.ReplaceAll(slug.Make(title), "-", " ")
+}
+
+// trigrams returns the three letter parts of the padded words of the text
+func trigrams(text string) map[string]bool {
+	result := map[string]bool{}
+	for _, word := range strings.Fields(text) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			result[string(runes[i:i+3])] = true
+		}
+	}
+	return result
+}
+
+// titleSimilarity returns the share of the trigrams the titles have in
+// common, or 1 when their slugs match
+func titleSimilarity(a, b string) float64 {
+	a, b = normalizeTitle(a), normalizeTitle(b)
+	if a == b {
+		return 1
+	}
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// getSimilarNodes returns the siblings of a new node with a similar title,
+// the most similar first
+func (m *Model) getSimilarNodes(domainId, parentId, level int, title string) (*NodeList, error) {
+	var candidates NodeList
+	err := m.db.Select(&candidates, "SELECT * FROM node WHERE domain_id = $1 AND parent_id = $2 AND level = $3 AND status = 1 ORDER BY id DESC LIMIT $4",
+		domainId, parentId, level, duplicateCandidates)
+	if err != nil {
+		return nil, err
+	}
+	var similar NodeList
+	scores := map[int]float64{}
+	for _, node := range candidates {
+		if score := titleSimilarity(title, node.Title); score >= duplicateThreshold {
+			scores[node.Id] = score
+			similar = append(similar, node)
+		}
+	}
+	sort.SliceStable(similar, func(i, j int) bool {
+		return scores[similar[i].Id] > scores[similar[j].Id]
+	})
+	if len(similar) > duplicateSuggestions {
+		similar = similar[:duplicateSuggestions]
+	}
+	return &similar, nil
+}
+
+// similarNodes looks for existing nodes like the posted one unless the
+// visitor confirmed posting it anyway. The spam guard is released when
+// there are suggestions so the form can be sent again right away.
+func (l *ListBoard) similarNodes(r *http.Request, node *Node) (*NodeList, error) {
+	if r.FormValue("duplicate_ok") != "" {
+		return &NodeList{}, nil
+	}
+	similar, err := l.m.getSimilarNodes(node.DomainId, node.ParentId, node.Level, node.Title)
+	if err == nil && len(*similar) > 0 {
+		l.sg.Release(r.RemoteAddr)
+	}
+	return similar, err
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		similar bool
+	}{
+		{"Best Go books", "best go books!", true},
+		{"Best Go books", "Best Go book", true},
+		{"Best Go books", "Best Rust books", false},
+		{"Best Go books", "Favorite movies", false},
+		{"", "Favorite movies", false},
+	}
+	for _, tt := range tests {
+		if got := titleSimilarity(tt.a, tt.b) >= duplicateThreshold; got != tt.similar {
+			t.Errorf("titleSimilarity(%q, %q) = %v, want similar %v", tt.a, tt.b, titleSimilarity(tt.a, tt.b), tt.similar)
+		}
+	}
+}
+
+func TestModelGetSimilarNodes(t *testing.T) {
+	m := newTestModel(t)
+	exact, _ := m.addNode(&Node{DomainId: 1, Title: "Best Go books", Status: statusEnabled, Level: levelRoot})
+	close, _ := m.addNode(&Node{DomainId: 1, Title: "The best Go books", Status: statusEnabled, Level: levelRoot})
+	m.addNode(&Node{DomainId: 1, Title: "Favorite movies", Status: statusEnabled, Level: levelRoot})
+	m.addNode(&Node{DomainId: 2, Title: "Best Go books", Status: statusEnabled, Level: levelRoot})
+	m.addNode(&Node{DomainId: 1, ParentId: exact, Title: "Best Go books", Status: statusEnabled, Level: levelList})
+	similar, err := m.getSimilarNodes(1, 0, levelRoot, "best go books")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := similar.Ids(); !reflect.DeepEqual(got, []int{exact, close}) {
+		t.Errorf("getSimilarNodes() = %v, want %v", got, []int{exact, close})
+	}
+}
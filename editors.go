@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ListEditor may edit a list and moderate its items next to its owner
+type ListEditor struct {
+	Id        int       `db:"id" json:"id"`
+	ListId    int       `db:"list_id" json:"list_id"`
+	DomainId  int       `db:"domain_id" json:"-"`
+	Tripcode  string    `db:"tripcode" json:"tripcode,omitempty"`
+	AccountId int       `db:"account_id" json:"account_id,omitempty"`
+	Username  string    `db:"username" json:"username,omitempty"`
+	Created   time.Time `db:"created" json:"created"`
+}
+
+type ListEditorList []ListEditor
+
+// credentials identify the editor like a posted password or login would
+func (e *ListEditor) credentials() Credentials {
+	return Credentials{Tripcode: e.Tripcode, AccountId: e.AccountId}
+}
+
+// parseEditor reads a tripcode or a ~username as they are shown next to the
+// posts
+func (m *Model) parseEditor(list *Node, value string, ln *Language) (*ListEditor, ValidationErrors) {
+	value = strings.TrimSpace(value)
+	editor := &ListEditor{ListId: list.Id, DomainId: list.DomainId}
+	if value == "" {
+		return nil, ValidationErrors{ln.Lang("Enter the tripcode or the username of the editor")}
+	}
+	if !strings.HasPrefix(value, "~") {
+		editor.Tripcode = value
+		return editor, nil
+	}
+	account, err := m.getAccountByUsername(list.DomainId, strings.TrimPrefix(value, "~"))
+	if err != nil {
+		return nil, ValidationErrors{ln.Lang("Account not found")}
+	}
+	editor.AccountId = account.Id
+	return editor, nil
+}
+
+func (m *Model) getEditors(domainId, listId int) (*ListEditorList, error) {
+	var el ListEditorList
+	err := m.db.Select(&el, `SELECT list_editor.*, COALESCE(account.username, '') AS username FROM list_editor
+		LEFT JOIN account ON account.id = list_editor.account_id
+		WHERE list_editor.domain_id = $1 AND list_editor.list_id = $2
+		ORDER BY list_editor.id`, domainId, listId)
+	return &el, err
+}
+
+// isEditor tells if the credentials belong to a co-editor of the list
+func (m *Model) isEditor(domainId, listId int, c Credentials) (bool, error) {
+	var count int
+	err := m.db.Get(&count, `SELECT COUNT(*) FROM list_editor WHERE domain_id = $1 AND list_id = $2 AND (
+			(tripcode != '' AND (tripcode = $3 OR tripcode = $4))
+			OR (account_id != 0 AND account_id = $5)
+		)`, domainId, listId, c.Tripcode, c.ClassicTripcode, c.AccountId)
+	return count > 0, err
+}
+
+// addEditor adds the co-editor unless the list already has it
+func (m *Model) addEditor(e *ListEditor) error {
+	exists, err := m.isEditor(e.DomainId, e.ListId, e.credentials())
+	if err != nil || exists {
+		return err
+	}
+	_, err = m.db.Exec("INSERT INTO list_editor (list_id, domain_id, tripcode, account_id, created) VALUES ($1, $2, $3, $4, $5)",
+		e.ListId, e.DomainId, e.Tripcode, e.AccountId, time.Now())
+	return err
+}
+
+func (m *Model) deleteEditor(domainId, listId, id int) error {
+	_, err := m.db.Exec("DELETE FROM list_editor WHERE domain_id = $1 AND list_id = $2 AND id = $3", domainId, listId, id)
+	return err
+}
+
+// canEditList tells if the credentials belong to the owner or a co-editor
+// of the list
+func (l *ListBoard) canEditList(list *Node, c Credentials) bool {
+	if c.owns(list) {
+		return true
+	}
+	editor, err := l.m.isEditor(list.DomainId, list.Id, c)
+	return err == nil && editor
+}
+
+// canManageEditors tells if the credentials may change the co-editors of
+// the list
+func canManageEditors(sc *SiteConfig, list *Node, c Credentials) bool {
+	return c.owns(list) || sc.isModerator(c)
+}
+
+// editorsHandler lists, adds and removes the co-editors of a list
+func (l *ListBoard) editorsHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+
+	var errors ValidationErrors
+	listId, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		return err
+	}
+	list, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	if list.Level != levelRoot {
+		return HTTPError{Message: "Only lists have editors", Code: http.StatusNotFound}
+	}
+
+	tr := l.tp.Get(sc.Language)
+	if r.Method == "POST" && !inHoneypot(r.FormValue("name")) {
+		if !canManageEditors(sc, list, l.credentials(r, sc.DomainId)) {
+			errors = append(errors, tr.Lang("Only the owner of the list can change its editors"))
+		} else if r.FormValue("remove") != "" {
+			editorId, _ := strconv.Atoi(r.FormValue("remove"))
+			if err := l.m.deleteEditor(sc.DomainId, listId, editorId); err != nil {
+				return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+			}
+		} else {
+			var editor *ListEditor
+			if editor, errors = l.m.parseEditor(list, r.FormValue("editor"), tr); len(errors) == 0 {
+				if err := l.m.addEditor(editor); err != nil {
+					return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+				}
+			}
+		}
+		if len(errors) == 0 {
+			http.Redirect(w, r, r.URL.String(), http.StatusFound)
+			return nil
+		}
+	}
+
+	editors, err := l.m.getEditors(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	title := tr.Lang("Editors")
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Item", list)
+	s.Set("Editors", editors)
+	s.Set("Action", title)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath(getUrl("", *list), list.Title)
+	s.AddPath("", title)
+	s.Set("Subtitle", title)
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("editors.html"))
+}
+
+type apiEditors struct {
+	Editors *ListEditorList `json:"editors"`
+}
+
+type apiEditor struct {
+	Editor string `json:"editor"`
+}
+
+// apiEditorsHandler lists and adds the co-editors of a list
+func (l *ListBoard) apiEditorsHandler(w http.ResponseWriter, r *http.Request) error {
+	listId, err := strconv.Atoi(mux.Vars(r)["listId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	scope := scopeRead
+	if r.Method != "GET" {
+		scope = scopeModerate
+	}
+	token, err := l.apiToken(r, sc.DomainId, scope)
+	if err != nil {
+		return err
+	}
+	list, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	if list.Level != levelRoot {
+		return HTTPError{Message: "Only lists have editors", Code: http.StatusNotFound}
+	}
+	if r.Method != "GET" && !canManageEditors(sc, list, token.credentials()) {
+		return HTTPError{Message: "List does not belong to the API token", Code: http.StatusForbidden}
+	}
+	code := http.StatusOK
+	switch r.Method {
+	case "POST":
+		var post apiEditor
+		if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{err.Error()}})
+		}
+		editor, errors := l.m.parseEditor(list, post.Editor, tr)
+		if len(errors) != 0 {
+			return writeJSON(w, http.StatusBadRequest, apiErrors{errors})
+		}
+		if err := l.m.addEditor(editor); err != nil {
+			return err
+		}
+		code = http.StatusCreated
+	case "DELETE":
+		editorId, err := strconv.Atoi(mux.Vars(r)["editorId"])
+		if err != nil {
+			return err
+		}
+		if err := l.m.deleteEditor(sc.DomainId, listId, editorId); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	editors, err := l.m.getEditors(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, code, apiEditors{Editors: editors})
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestModelListEditors(t *testing.T) {
+	m := newTestModel(t)
+	owner := Credentials{Tripcode: "!owner"}
+	editor := Credentials{Tripcode: "!editor"}
+	stranger := Credentials{Tripcode: "!stranger"}
+	listId, _ := m.addNode(&Node{DomainId: 1, Title: "list", Tripcode: owner.Tripcode, Status: statusEnabled, Level: levelRoot})
+	list, _ := m.getNode(1, listId)
+	if err := m.addEditor(&ListEditor{ListId: listId, DomainId: 1, Tripcode: editor.Tripcode}); err != nil {
+		t.Fatal(err)
+	}
+	// Adding the same editor again is ignored
+	m.addEditor(&ListEditor{ListId: listId, DomainId: 1, Tripcode: editor.Tripcode})
+	if editors, _ := m.getEditors(1, listId); len(*editors) != 1 {
+		t.Errorf("getEditors() = %d editors, want 1", len(*editors))
+	}
+
+	t.Run("editors edit the list", func(t *testing.T) {
+		for _, c := range []Credentials{stranger, editor} {
+			m.editNode(&Node{Id: listId, DomainId: 1, Title: "by " + c.Tripcode, Level: levelRoot}, c)
+		}
+		if got, _ := m.getNode(1, listId); got.Title != "by !editor" {
+			t.Errorf("editNode() title = %q, want %q", got.Title, "by !editor")
+		}
+	})
+
+	t.Run("editors close the list", func(t *testing.T) {
+		if changed, _ := m.setClosed(list, true, stranger); changed {
+			t.Error("setClosed() by a stranger changed the list")
+		}
+		if changed, _ := m.setClosed(list, true, editor); !changed {
+			t.Error("setClosed() by an editor did not change the list")
+		}
+	})
+
+	t.Run("owners and editors delete items", func(t *testing.T) {
+		for _, c := range []Credentials{owner, editor} {
+			itemId, _ := m.addNode(&Node{DomainId: 1, ParentId: listId, Title: "item", Tripcode: "!author", Status: statusEnabled, Level: levelList})
+			item, _ := m.getNode(1, itemId)
+			if deleted, _ := m.deleteNode(item, stranger); deleted {
+				t.Error("deleteNode() by a stranger deleted the item")
+			}
+			if deleted, _ := m.deleteNode(item, c); !deleted {
+				t.Errorf("deleteNode() by %s did not delete the item", c.Tripcode)
+			}
+		}
+	})
+
+	t.Run("editors don't delete the list", func(t *testing.T) {
+		if deleted, _ := m.deleteNode(list, editor); deleted {
+			t.Error("deleteNode() by an editor deleted the list")
+		}
+	})
+
+	editors, _ := m.getEditors(1, listId)
+	m.deleteEditor(1, listId, (*editors)[0].Id)
+	if ok, _ := m.isEditor(1, listId, editor); ok {
+		t.Error("isEditor() = true after deleteEditor()")
+	}
+}
@@ -1,5 +1,12 @@
 package main
 
+import "errors"
+
+var errUnsupportedImage = errors.New("unsupported image")
+var errInvalidCursor = errors.New("invalid cursor")
+var errNoKeyset = errors.New("order can't be paginated with a cursor")
+var errAlreadyVoted = errors.New("already voted")
+
 type HTTPError struct {
 	Err     error
 	Message string
@@ -7,5 +14,8 @@ type HTTPError struct {
 }
 
 func (e HTTPError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
 	return e.Err.Error()
 }
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	sortUpdated = "updated"
+	sortNewest  = "newest"
+	sortVotes   = "votes"
+	sortActive  = "active"
+	sortTitle   = "title"
+
+	maxFilterDays = 3650
+)
+
+var indexSorts = map[string]string{
+	sortUpdated: "updated DESC",
+	sortNewest:  "id DESC",
+	sortVotes:   "vote DESC, id DESC",
+	sortActive:  "(SELECT COUNT(*) FROM node i WHERE i.parent_id = node.id AND i.status = 1) DESC, updated DESC",
+	sortTitle:   "title COLLATE NOCASE, id",
+}
+
+// indexSortOptions are offered in the index toolbar
+var indexSortOptions = []selectOption{
+	{sortUpdated, "Recently updated"},
+	{sortNewest, "Newest"},
+	{sortVotes, "Most voted"},
+	{sortActive, "Most active"},
+	{sortTitle, "Alphabetical"},
+}
+
+// indexDayOptions are the age filters offered in the index toolbar
+var indexDayOptions = []int{1, 7, 30, 365}
+
+// IndexFilter selects and orders the lists shown on the index and tag pages
+type IndexFilter struct {
+	Sort     string
+	Days     int
+	MinVotes int
+	Tripcode bool
+	Tags     []string
+	// Unpinned leaves out the pinned lists shown above the others
+	Unpinned bool
+}
+
+// parseIndexFilter reads the filter from the query ignoring invalid values
+func parseIndexFilter(q url.Values) IndexFilter {
+	f := IndexFilter{Sort: sortUpdated}
+	if _, ok := indexSorts[q.Get("sort")]; ok {
+		f.Sort = q.Get("sort")
+	}
+	if days, err := strconv.Atoi(q.Get("days")); err == nil && days > 0 && days <= maxFilterDays {
+		f.Days = days
+	}
+	if votes, err := strconv.Atoi(q.Get("min_votes")); err == nil && votes > 0 {
+		f.MinVotes = votes
+	}
+	f.Tripcode = q.Get("tripcode") != ""
+	return f
+}
+
+// query returns the parameters of the filter that differ from the defaults
+func (f IndexFilter) query() url.Values {
+	q := url.Values{}
+	if f.Sort != sortUpdated && f.Sort != "" {
+		q.Set("sort", f.Sort)
+	}
+	if f.Days > 0 {
+		q.Set("days", strconv.Itoa(f.Days))
+	}
+	if f.MinVotes > 0 {
+		q.Set("min_votes", strconv.Itoa(f.MinVotes))
+	}
+	if f.Tripcode {
+		q.Set("tripcode", "1")
+	}
+	return q
+}
+
+// pageURL is the base URL of the pagination keeping the filter
+func (f IndexFilter) pageURL() string {
+	return "?" + f.query().Encode()
+}
+
+func (f IndexFilter) order() string {
+	if order, ok := indexSorts[f.Sort]; ok {
+		return order
+	}
+	return indexSorts[sortUpdated]
+}
+
+// where returns the conditions of the filter and their arguments
+func (f IndexFilter) where(domainId int) (string, []interface{}, error) {
+	conditions := []string{"domain_id = ? AND parent_id = 0 AND status = 1"}
+	args := []interface{}{domainId}
+	if f.Days > 0 {
+		conditions = append(conditions, "created >= ?")
+		args = append(args, time.Now().AddDate(0, 0, -f.Days))
+	}
+	if f.MinVotes > 0 {
+		conditions = append(conditions, "vote >= ?")
+		args = append(args, f.MinVotes)
+	}
+	if f.Tripcode {
+		conditions = append(conditions, "tripcode != ''")
+	}
+	if f.Unpinned {
+		conditions = append(conditions, "NOT ("+pinnedCondition+")")
+		args = append(args, pinNow())
+	}
+	if len(f.Tags) > 0 {
+		conditions = append(conditions, `id IN (
+			SELECT node_id FROM node_tag WHERE domain_id = ? AND slug IN (?)
+			GROUP BY node_id HAVING COUNT(*) = ?
+		)`)
+		args = append(args, domainId, f.Tags, len(f.Tags))
+	}
+	query, args, err := sqlx.In(strings.Join(conditions, " AND "), args...)
+	return query, args, err
+}
+
+// getLists returns the lists matching the filter
+func (m *Model) getLists(domainId int, f IndexFilter, count, offset int) (*NodeList, error) {
+	var nl NodeList
+	where, args, err := f.where(domainId)
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT * FROM node WHERE " + where + " ORDER BY " + f.order() + " LIMIT ?, ?"
+	err = m.db.Select(&nl, m.db.Rebind(query), append(args, offset, count)...)
+	return &nl, err
+}
+
+func (m *Model) mustGetLists(domainId int, f IndexFilter, count, offset int) *NodeList {
+	nl, err := m.getLists(domainId, f, count, offset)
+	if err != nil {
+		panic(err)
+	}
+	return nl
+}
+
+// getListsTotal returns the number of lists matching the filter
+func (m *Model) getListsTotal(domainId int, f IndexFilter) (int, error) {
+	var total int
+	where, args, err := f.where(domainId)
+	if err != nil {
+		return 0, err
+	}
+	err = m.db.Get(&total, m.db.Rebind("SELECT COUNT(*) FROM node WHERE "+where), args...)
+	return total, err
+}
+
+// setListsSession adds the filtered lists of the page to the session
+func (l *ListBoard) setListsSession(s *Session, r *http.Request, sc *SiteConfig, f IndexFilter) error {
+	page := getPageNumber(r.URL.Query().Get("page"))
+	lists, err := l.m.getLists(sc.DomainId, f, itemsPerPage, page*itemsPerPage)
+	if err != nil {
+		return err
+	}
+	total, err := l.m.getListsTotal(sc.DomainId, f)
+	if err != nil {
+		return err
+	}
+	s.Set("Filter", f)
+	s.Set("SortOptions", indexSortOptions)
+	s.Set("DayOptions", indexDayOptions)
+	s.Set("Lists", lists)
+	s.Set("ListTags", l.m.mustGetTags(sc.DomainId, lists.Ids()))
+	s.Set("Progress", l.m.mustGetProgress(sc.DomainId, lists))
+	s.Set("Pagination", Pagination(PaginationConfig{
+		page:  page + 1,
+		ipp:   itemsPerPage,
+		total: total,
+		url:   f.pageURL(),
+		param: "page",
+	}))
+	return nil
+}
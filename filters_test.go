@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseIndexFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  IndexFilter
+	}{
+		{"defaults", "", IndexFilter{Sort: sortUpdated}},
+		{"all filters", "sort=votes&days=7&min_votes=3&tripcode=1", IndexFilter{Sort: sortVotes, Days: 7, MinVotes: 3, Tripcode: true}},
+		{"invalid values", "sort=bogus&days=-1&min_votes=x", IndexFilter{Sort: sortUpdated}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			if got := parseIndexFilter(q); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIndexFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexFilterPageURL(t *testing.T) {
+	f := IndexFilter{Sort: sortTitle, MinVotes: 2}
+	if got := f.pageURL(); got != "?min_votes=2&sort=title" {
+		t.Errorf("pageURL() = %v, want ?min_votes=2&sort=title", got)
+	}
+	pages := Pagination(PaginationConfig{ipp: 10, page: 1, total: 20, url: f.pageURL(), param: "page"})
+	if pages[1].URL != "?min_votes=2&page=2&sort=title" {
+		t.Errorf("Pagination() url = %v, expected the filter to be kept", pages[1].URL)
+	}
+}
+
+func TestModelGetLists(t *testing.T) {
+	m := newTestModel(t)
+	old, _ := m.addNode(&Node{DomainId: 1, Title: "b old", Tripcode: "abc", Status: statusEnabled, Level: levelRoot})
+	m.db.Exec("UPDATE node SET created = $1, vote = 5 WHERE id = $2", time.Now().AddDate(0, 0, -10), old)
+	recent, _ := m.addNode(&Node{DomainId: 1, Title: "a recent", Status: statusEnabled, Level: levelRoot})
+	tests := []struct {
+		name   string
+		filter IndexFilter
+		want   []int
+	}{
+		{"alphabetical", IndexFilter{Sort: sortTitle}, []int{recent, old}},
+		{"newest", IndexFilter{Sort: sortNewest}, []int{recent, old}},
+		{"last days", IndexFilter{Days: 7}, []int{recent}},
+		{"minimum votes", IndexFilter{MinVotes: 1}, []int{old}},
+		{"has tripcode", IndexFilter{Tripcode: true}, []int{old}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := m.getLists(1, tt.filter, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := nodes.Ids(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getLists() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
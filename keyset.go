@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// sortKey is a column of a keyset pagination order
+type sortKey struct {
+	column string
+	desc   bool
+	value  func(n *Node) interface{}
+}
+
+var idKey = sortKey{"id", false, func(n *Node) interface{} { return n.Id }}
+var idDescKey = sortKey{"id", true, func(n *Node) interface{} { return n.Id }}
+
+// keysets holds the orders that can be paginated with cursors. Each ends
+// with the id so the position of every node is unique.
+var keysets = map[string][]sortKey{
+	"vote DESC, id":            {{"vote", true, func(n *Node) interface{} { return n.Vote }}, idKey},
+	"vote DESC, id DESC":       {{"vote", true, func(n *Node) interface{} { return n.Vote }}, idDescKey},
+	"wilson DESC, id":          {{"wilson", true, func(n *Node) interface{} { return n.Wilson }}, idKey},
+	"hot DESC, id":             {{"hot", true, func(n *Node) interface{} { return n.Hot }}, idKey},
+	"controversy DESC, id":     {{"controversy", true, func(n *Node) interface{} { return n.Controversy }}, idKey},
+	"title COLLATE NOCASE, id": {{"title COLLATE NOCASE", false, func(n *Node) interface{} { return n.Title }}, idKey},
+	"id DESC":                  {idDescKey},
+	"id":                       {idKey},
+}
+
+// encodeCursor returns the position of the node in the order
+func encodeCursor(keys []sortKey, n *Node) string {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = key.value(n)
+	}
+	b, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(keys []sortKey, cursor string) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var values []interface{}
+	if err := json.Unmarshal(b, &values); err != nil || len(values) != len(keys) {
+		return nil, errInvalidCursor
+	}
+	return values, nil
+}
+
+// keysetCondition matches the nodes after the cursor values in the order
+func keysetCondition(keys []sortKey, values []interface{}) (string, []interface{}) {
+	var or []string
+	var args []interface{}
+	for i, key := range keys {
+		var and []string
+		for j := 0; j < i; j++ {
+			and = append(and, keys[j].column+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if key.desc {
+			op = " < ?"
+		}
+		and = append(and, key.column+op)
+		args = append(args, values[i])
+		or = append(or, "("+strings.Join(and, " AND ")+")")
+	}
+	return "(" + strings.Join(or, " OR ") + ")", args
+}
+
+// selectPage selects up to count nodes after the cursor and returns the
+// cursor of the next page or an empty string on the last page
+func (m *Model) selectPage(where string, args []interface{}, orderBy, cursor string, count int) (*NodeList, string, error) {
+	keys, ok := keysets[orderBy]
+	if !ok {
+		return nil, "", errNoKeyset
+	}
+	if cursor != "" {
+		values, err := decodeCursor(keys, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		condition, cursorArgs := keysetCondition(keys, values)
+		where += " AND " + condition
+		args = append(args, cursorArgs...)
+	}
+	var nl NodeList
+	query := "SELECT * FROM node WHERE " + where + " ORDER BY " + orderBy + " LIMIT ?"
+	if err := m.db.Select(&nl, m.db.Rebind(query), append(args, count+1)...); err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(nl) > count {
+		nl = nl[:count]
+		next = encodeCursor(keys, &nl[count-1])
+	}
+	return &nl, next, nil
+}
+
+// getChildNodesPage returns a page of the children after the cursor
+func (m *Model) getChildNodesPage(domainId, parentNodeId, count int, orderBy, cursor string) (*NodeList, string, error) {
+	return m.selectPage("domain_id = ? AND status = 1 AND parent_id = ?", []interface{}{domainId, parentNodeId}, orderBy, cursor, count)
+}
+
+// getListsPage returns a page of the filtered lists after the cursor
+func (m *Model) getListsPage(domainId int, f IndexFilter, count int, cursor string) (*NodeList, string, error) {
+	where, args, err := f.where(domainId)
+	if err != nil {
+		return nil, "", err
+	}
+	return m.selectPage(where, args, f.order(), cursor, count)
+}
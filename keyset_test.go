@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	keys := keysets["vote DESC, id"]
+	cursor := encodeCursor(keys, &Node{Id: 12, Vote: -3})
+	values, err := decodeCursor(keys, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{float64(-3), float64(12)}) {
+		t.Errorf("decodeCursor() = %v, want [-3 12]", values)
+	}
+	for _, bad := range []string{"!!", "WzFd", "bm90IGpzb24"} {
+		if _, err := decodeCursor(keys, bad); err != errInvalidCursor {
+			t.Errorf("decodeCursor(%q) error = %v, want errInvalidCursor", bad, err)
+		}
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	where, args := keysetCondition(keysets["vote DESC, id"], []interface{}{5, 7})
+	if where != "((vote < ?) OR (vote = ? AND id > ?))" {
+		t.Errorf("keysetCondition() = %v", where)
+	}
+	if !reflect.DeepEqual(args, []interface{}{5, 5, 7}) {
+		t.Errorf("keysetCondition() args = %v, want [5 5 7]", args)
+	}
+}
+
+func TestModelGetChildNodesPage(t *testing.T) {
+	m := newTestModel(t)
+	list, _ := m.addNode(&Node{DomainId: 1, Title: "list", Status: statusEnabled, Level: levelRoot})
+	var want []int
+	for _, vote := range []int{2, 5, 2, 0, 5} {
+		id, _ := m.addNode(&Node{DomainId: 1, ParentId: list, Title: "item", Status: statusEnabled, Level: levelList})
+		m.db.Exec("UPDATE node SET vote = $1 WHERE id = $2", vote, id)
+		want = append(want, id)
+	}
+	want = []int{want[1], want[4], want[0], want[2], want[3]}
+	var got []int
+	cursor := ""
+	for i := 0; i < 10; i++ {
+		nodes, next, err := m.getChildNodesPage(1, list, 2, "vote DESC, id", cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, nodes.Ids()...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getChildNodesPage() = %v, want %v", got, want)
+	}
+	if _, _, err := m.getChildNodesPage(1, list, 2, "updated DESC", ""); err != errNoKeyset {
+		t.Errorf("getChildNodesPage() error = %v, want errNoKeyset", err)
+	}
+}
@@ -22,11 +22,29 @@ const (
 	levelVote
 )
 
+// statusDeleted marks nodes removed by their author
+const statusDeleted = 2
+
+// statusMerged marks lists merged into another list
+const statusMerged = 3
+
+// previewBlockExpire is the time between two previews from the same address
+const previewBlockExpire = "1s"
+
 type ListBoard struct {
 	config *Config
 	m      *Model
 	tp     *TransPool
 	sg     *SpamGuard
+	apiSg  *SpamGuard
+	// apiReadSg limits the read requests of a token
+	apiReadSg *SpamGuard
+	// uploadSg limits the uploads of an address
+	uploadSg *SpamGuard
+	// previewSg limits the previews of an address
+	previewSg *SpamGuard
+	store     BlobStore
+	fetcher   Fetcher
 }
 
 type ValidationErrors []string
@@ -52,6 +70,12 @@ func (l *ListBoard) Run(args []string) {
 	}
 
 	l.sg = NewSpamGuard(l.config.PostBlockExpire)
+	l.apiSg = NewSpamGuard(l.config.ApiPostBlockExpire)
+	l.apiReadSg = NewSpamGuard(l.config.ApiReadBlockExpire)
+	l.uploadSg = NewSpamGuard(l.config.Uploads.BlockExpire)
+	l.previewSg = NewSpamGuard(previewBlockExpire)
+	l.store = NewLocalStore(l.config.Uploads.Dir, l.config.Uploads.URL)
+	l.fetcher = NewHttpFetcher()
 
 	l.m = NewModel(l.config)
 	if err = l.m.Init(l.config); err != nil {
@@ -60,16 +84,57 @@ func (l *ListBoard) Run(args []string) {
 
 	l.tp = NewTransPool(l.config.Translations)
 
+	if len(args) > 2 {
+		if err = l.runCommand(args[2]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	r := mux.NewRouter()
 	r.HandleFunc("/", appHandler(l.indexHandler).ServeHTTP).Methods("GET")
 	r.HandleFunc("/feed.xml", appHandler(l.feedHandler).ServeHTTP).Methods("GET")
 	r.HandleFunc("/all.xml", appHandler(l.feedAllHandler).ServeHTTP).Methods("GET")
+	r.HandleFunc("/tag/{tags}/feed.xml", appHandler(l.tagFeedHandler).ServeHTTP).Methods("GET")
+	r.HandleFunc("/tag/{tags}", appHandler(l.tagHandler).ServeHTTP).Methods("GET")
 	r.HandleFunc("/sitemap.xml", appHandler(l.sitemapHandler).ServeHTTP).Methods("GET")
 
-	r.HandleFunc("/add.html", appHandler(l.addFormHandler).ServeHTTP).Methods("GET", "POST")
-	r.HandleFunc("/edit.html", appHandler(l.editFormHandler).ServeHTTP).Methods("GET", "POST")
-	r.HandleFunc("/list/{listId}/{slug}", appHandler(l.listHandler).ServeHTTP).Methods("GET", "POST")
-	r.HandleFunc("/vote/{itemId}/{slug}", appHandler(l.voteHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/add.html", l.csrf(l.addFormHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/edit.html", l.csrf(l.editFormHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/delete.html", l.csrf(l.deleteFormHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/close.html", l.csrf(l.closeFormHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/move.html", l.csrf(l.moveFormHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/pin.html", l.csrf(l.pinFormHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/editors.html", l.csrf(l.editorsHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/split.html", l.csrf(l.splitFormHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/done.html", l.csrf(l.doneFormHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/clone.html", l.csrf(l.cloneFormHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/register.html", l.csrf(l.registerHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/login.html", l.csrf(l.loginHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/logout.html", l.csrf(l.logoutHandler).ServeHTTP).Methods("POST")
+	r.HandleFunc("/preview", l.csrf(l.previewHandler).ServeHTTP).Methods("POST")
+	r.HandleFunc("/upload", l.limitUpload(l.csrf(l.uploadHandler)).ServeHTTP).Methods("POST")
+	r.HandleFunc("/tokens.html", l.csrf(l.tokensHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/results/{listId:[0-9]+}.csv", appHandler(l.rankedExportHandler).ServeHTTP).Methods("GET")
+	r.HandleFunc("/list/{listId}/feed.xml", appHandler(l.listFeedHandler).ServeHTTP).Methods("GET")
+	r.HandleFunc("/list/{listId}/{slug}", l.csrf(l.listHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/vote/{itemId}/{slug}", l.csrf(l.voteHandler).ServeHTTP).Methods("GET", "POST")
+
+	r.HandleFunc("/api/lists", appHandler(l.apiListsHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/api/list/{listId}", appHandler(l.apiListHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/api/vote/{itemId}", appHandler(l.apiVoteHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/api/poll/{listId}", appHandler(l.apiPollHandler).ServeHTTP).Methods("POST")
+	r.HandleFunc("/api/ballot/{listId}", appHandler(l.apiBallotHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/api/close/{listId}", appHandler(l.apiCloseHandler).ServeHTTP).Methods("POST")
+	r.HandleFunc("/api/pin/{listId}", appHandler(l.apiPinHandler).ServeHTTP).Methods("POST")
+	r.HandleFunc("/api/editors/{listId}", appHandler(l.apiEditorsHandler).ServeHTTP).Methods("GET", "POST")
+	r.HandleFunc("/api/editors/{listId}/{editorId}", appHandler(l.apiEditorsHandler).ServeHTTP).Methods("DELETE")
+	r.HandleFunc("/api/move/{nodeId}", appHandler(l.apiMoveHandler).ServeHTTP).Methods("POST")
+	r.HandleFunc("/api/done/{itemId}", appHandler(l.apiDoneHandler).ServeHTTP).Methods("POST")
+	r.HandleFunc("/api/node/{nodeId}", appHandler(l.apiNodeHandler).ServeHTTP).Methods("DELETE")
+
+	// Uploaded images
+	r.PathPrefix(l.config.Uploads.URL).Handler(http.StripPrefix(l.config.Uploads.URL, http.FileServer(http.Dir(l.config.Uploads.Dir))))
 
 	// Static assets
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./public_html")))
@@ -117,18 +182,23 @@ func getPageNumber(pageStr string) int {
 }
 
 func (l *ListBoard) indexHandler(w http.ResponseWriter, r *http.Request) error {
-	page := getPageNumber(r.URL.Query().Get("page"))
 	sc := l.config.getSiteConfig(l.getToken(r))
-	s := NewSession(sc, l.tp.Get(sc.Language))
+	s := l.session(w, r, sc, l.tp.Get(sc.Language))
 	s.AddPath("", s.Lang("Home"))
-	s.Set("Lists", l.m.mustGetChildNodes(sc.DomainId, 0, itemsPerPage, (page*itemsPerPage), "updated DESC"))
-	s.Set("Pagination", Pagination(PaginationConfig{
-		page:  page + 1,
-		ipp:   itemsPerPage,
-		total: l.m.mustGetTotal(sc.DomainId, 0),
-		url:   "?",
-		param: "page",
-	}))
+	// Pinned lists are shown on top and left out of the other lists
+	if err := l.setPinnedSession(s, sc); err != nil {
+		return err
+	}
+	f := parseIndexFilter(r.URL.Query())
+	f.Unpinned = true
+	if err := l.setListsSession(s, r, sc, f); err != nil {
+		return err
+	}
+	cloud, err := l.m.getTagCloud(sc.DomainId, tagCloudSize)
+	if err != nil {
+		return err
+	}
+	s.Set("TagCloud", cloud)
 	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("index.html"))
 }
 
@@ -137,13 +207,22 @@ func (l *ListBoard) addFormHandler(w http.ResponseWriter, r *http.Request) error
 
 	var errors ValidationErrors
 	var node Node
+	var similar *NodeList
 	tr := l.tp.Get(sc.Language)
+	// The template stays in the URL when the form is posted
+	tmpl := sc.listTemplate(r.URL.Query().Get("template"))
 	if r.Method == "POST" {
 		if !inHoneypot(r.FormValue("name")) {
-			node, errors = l.validateForm(r, sc.DomainId, 0, levelRoot, tr)
+			node, errors = l.validateForm(r, sc, 0, levelRoot, tr)
 			if len(errors) == 0 {
+				var err error
+				if similar, err = l.similarNodes(r, &node); err != nil {
+					return err
+				}
+			}
+			if len(errors) == 0 && len(*similar) == 0 {
 				// save and redirect
-				id, err := l.m.addNode(&node)
+				id, err := l.m.addList(&node, tmpl.defaultItems(&node))
 				if err != nil {
 					return &HTTPError{Err: err, Code: http.StatusInternalServerError}
 				}
@@ -151,10 +230,18 @@ func (l *ListBoard) addFormHandler(w http.ResponseWriter, r *http.Request) error
 				http.Redirect(w, r, url, http.StatusFound)
 			}
 		}
+	} else if tmpl != nil {
+		node = tmpl.node(sc.DomainId, time.Now())
 	}
-	s := NewSession(sc, tr)
+	s := l.session(w, r, sc, tr)
 	s.Set("Errors", errors)
 	s.Set("Form", node)
+	s.Set("Similar", similar)
+	s.Set("ShowRanking", true)
+	s.Set("Rankings", rankingOptions)
+	s.Set("ShowKind", true)
+	s.Set("ListTemplates", sc.ListTemplates)
+	s.Set("ListTemplate", tmpl)
 	s.AddPath("/", s.Lang("Home"))
 	s.AddPath("", s.Lang("New list"))
 	s.Set("Subtitle", s.Lang("New list"))
@@ -186,11 +273,21 @@ func (l *ListBoard) editFormHandler(w http.ResponseWriter, r *http.Request) erro
 			if parentId, err = strconv.Atoi(r.FormValue("parent_id")); err != nil {
 				level = 0
 			}
-			node, errors = l.validateForm(r, sc.DomainId, parentId, level, tr)
+			node, errors = l.validateForm(r, sc, parentId, level, tr)
+			if len(errors) == 0 && level == levelRoot {
+				// The kind of a list is not edited, polls keep their choices
+				stored, err := l.m.getNode(sc.DomainId, nodeId)
+				if err != nil {
+					return err
+				}
+				settings := node.ListSettings()
+				settings.Multiple = stored.ListSettings().Multiple
+				node.Settings = settings.encode()
+			}
 			if len(errors) == 0 {
 				node.Id = nodeId
 				// save and redirect
-				if err := l.m.editNode(&node); err != nil {
+				if err := l.m.editNode(&node, l.credentials(r, sc.DomainId)); err != nil {
 					return &HTTPError{Err: err, Code: http.StatusInternalServerError}
 				}
 				url := getUrl("http://"+r.Host, node)
@@ -203,16 +300,91 @@ func (l *ListBoard) editFormHandler(w http.ResponseWriter, r *http.Request) erro
 	if err != nil {
 		return err
 	}
+	if item.Tags, err = l.m.getTagNames(sc.DomainId, nodeId); err != nil {
+		return err
+	}
 
-	s := NewSession(sc, tr)
+	s := l.session(w, r, sc, tr)
 	s.Set("Errors", errors)
 	s.Set("Form", item)
+	s.Set("ShowRanking", item.Level == levelRoot)
+	s.Set("Rankings", rankingOptions)
 	s.AddPath("/", s.Lang("Home"))
 	s.AddPath("", s.Lang("Edit"))
 	s.Set("Subtitle", s.Lang("Edit"))
 	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("edit.html"), sc.templatePath("form.html"))
 }
 
+func (l *ListBoard) deleteFormHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+
+	var errors ValidationErrors
+	var nodeId int
+	var err error
+	id := r.URL.Query().Get("id")
+
+	if nodeId, err = strconv.Atoi(id); err != nil {
+		return err
+	}
+
+	item, err := l.m.getNode(sc.DomainId, nodeId)
+	if err != nil {
+		return err
+	}
+
+	tr := l.tp.Get(sc.Language)
+	if r.Method == "POST" {
+		if !inHoneypot(r.FormValue("name")) {
+			if errors, err = l.deleteErrors(item, tr); err != nil {
+				return err
+			}
+			if len(errors) == 0 {
+				deleted, err := l.m.deleteNode(item, l.credentials(r, sc.DomainId))
+				if err != nil {
+					return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+				}
+				if deleted {
+					url := getUrl("http://"+r.Host, *item)
+					http.Redirect(w, r, url, http.StatusFound)
+					return nil
+				}
+				errors = append(errors, tr.Lang("Wrong tripcode password"))
+			}
+		}
+	}
+
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Item", item)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath("", s.Lang("Delete"))
+	s.Set("Subtitle", s.Lang("Delete"))
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("delete.html"))
+}
+
+// previewResult is the rendered body and the validation errors of a preview
+type previewResult struct {
+	Rendered string           `json:"rendered"`
+	Errors   ValidationErrors `json:"errors"`
+}
+
+// previewHandler renders the posted form and validates it without saving
+// or fetching the link previews
+func (l *ListBoard) previewHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	if !l.previewSg.CanPost(remoteHost(r)) {
+		return writeJSON(w, http.StatusTooManyRequests, previewResult{Errors: ValidationErrors{tr.Lang("Please wait before posting again")}})
+	}
+	node := Node{
+		DomainId: sc.DomainId,
+		Title:    strings.TrimSpace(r.FormValue("title")),
+		Body:     r.FormValue("body"),
+	}
+	errors := l.previewNode(&node, sc, tr)
+	return writeJSON(w, http.StatusOK, previewResult{node.Rendered, errors})
+}
+
 func (l *ListBoard) listHandler(w http.ResponseWriter, r *http.Request) error {
 	vars := mux.Vars(r)
 	listId, err := strconv.Atoi(vars["listId"])
@@ -222,14 +394,41 @@ func (l *ListBoard) listHandler(w http.ResponseWriter, r *http.Request) error {
 	}
 	sc := l.config.getSiteConfig(l.getToken(r))
 	tr := l.tp.Get(sc.Language)
+	list, err := l.m.getNode(sc.DomainId, listId)
+	if err == sql.ErrNoRows && r.Method == "GET" && l.redirectMerged(w, r, sc, listId, "/list/", "/"+vars["slug"]) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if list.IsPoll() {
+		return l.pollHandler(w, r, sc, tr, list)
+	}
 
-	var errors ValidationErrors
+	var errors, ballotErrors ValidationErrors
 	var node Node
+	var similar *NodeList
 
-	if r.Method == "POST" {
+	if r.Method == "POST" && list.IsRanked() && r.FormValue("ballot") != "" {
+		if ballotErrors = l.castBallot(r, list, tr); len(ballotErrors) == 0 {
+			http.Redirect(w, r, r.URL.String()+"#ballot", http.StatusFound)
+			return nil
+		}
+	} else if r.Method == "POST" {
 		if !inHoneypot(r.FormValue("name")) {
-			node, errors = l.validateForm(r, sc.DomainId, listId, levelList, tr)
+			node, errors = l.validateForm(r, sc, listId, levelList, tr)
+			errors = append(closedErrors(list, tr), errors...)
+			itemErrors, err := l.itemErrors(list, l.credentials(r, sc.DomainId), tr)
+			if err != nil {
+				return err
+			}
+			errors = append(errors, itemErrors...)
 			if len(errors) == 0 {
+				if similar, err = l.similarNodes(r, &node); err != nil {
+					return err
+				}
+			}
+			if len(errors) == 0 && len(*similar) == 0 {
 				// save and redirect
 				id, err := l.m.addNode(&node)
 				if err != nil {
@@ -244,30 +443,43 @@ func (l *ListBoard) listHandler(w http.ResponseWriter, r *http.Request) error {
 			}
 		}
 	}
-	s := NewSession(sc, tr)
+	s := l.session(w, r, sc, tr)
 
 	s.Set("Errors", errors)
 	s.Set("Form", node)
-	list, err := l.m.getNode(sc.DomainId, listId)
-	if err != nil {
-		return err
-	}
+	s.Set("Similar", similar)
 	page := getPageNumber(r.URL.Query().Get("page"))
 	s.Set("List", list)
-	s.Set("Items", l.m.mustGetChildNodes(sc.DomainId, listId, itemsPerPage, (page*itemsPerPage), "vote DESC, created"))
+	s.Set("ListTags", l.m.mustGetTags(sc.DomainId, []int{list.Id}))
+	if list.IsRanked() {
+		s.Set("BallotErrors", ballotErrors)
+		if err := l.setRankedSession(s, r, list); err != nil {
+			return err
+		}
+	}
+	// Items pinned by the editors of the list come first, done checklist
+	// items last
+	order := pinnedItemsOrder + rankingOrder(list.Ranking, sc.Ranking)
+	if list.IsChecklist() {
+		order = doneItemsOrder + order
+		s.Set("Progress", l.m.mustGetProgress(sc.DomainId, &NodeList{*list})[list.Id])
+	}
+	items := l.m.mustGetChildNodesWithDeleted(sc.DomainId, listId, itemsPerPage, (page * itemsPerPage), order)
+	s.Set("Items", items)
+	s.Set("Backlinks", l.m.mustGetBacklinks(sc.DomainId, append(items.Ids(), list.Id)))
 	s.Set("FormTitle", s.Lang("New suggestion"))
 	s.Set("Subtitle", list.Title)
 	s.Set("Description", list.Title)
 	s.Set("Pagination", Pagination(PaginationConfig{
 		page:  page + 1,
 		ipp:   itemsPerPage,
-		total: l.m.mustGetTotal(sc.DomainId, listId),
+		total: l.m.mustGetTotalWithDeleted(sc.DomainId, listId),
 		url:   "?",
 		param: "page",
 	}))
 	s.AddPath("/", s.Lang("Home"))
 	s.AddPath("", list.Title)
-	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("list.html"), sc.templatePath("form.html"))
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("list.html"), sc.templatePath("ranked.html"), sc.templatePath("form.html"))
 }
 
 func (l *ListBoard) voteHandler(w http.ResponseWriter, r *http.Request) error {
@@ -285,9 +497,19 @@ func (l *ListBoard) voteHandler(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
+	list, err := l.m.getNode(sc.DomainId, item.ParentId)
+	if err != nil {
+		return err
+	}
 	if r.Method == "POST" {
 		if !inHoneypot(r.FormValue("name")) {
-			node, errors = l.validateForm(r, sc.DomainId, itemId, levelVote, tr)
+			node, errors = l.validateForm(r, sc, itemId, levelVote, tr)
+			errors = append(closedErrors(list, tr), errors...)
+			errors = append(errors, votingErrors(list, l.credentials(r, sc.DomainId).identified(), tr)...)
+			if !list.ShowItemVotes() {
+				// only comments, the items are voted in the list or not at all
+				node.Vote = 0
+			}
 			if len(errors) == 0 {
 				id, err := l.m.addNode(&node)
 				if err != nil {
@@ -308,22 +530,20 @@ func (l *ListBoard) voteHandler(w http.ResponseWriter, r *http.Request) error {
 			}
 		}
 	}
-	s := NewSession(sc, tr)
+	s := l.session(w, r, sc, tr)
 	s.Set("Subtitle", item.Title)
 	s.Set("Description", item.Title)
-	s.Set("ShowVote", true)
+	s.Set("ShowVote", list.ShowItemVotes())
 	s.Set("Errors", errors)
-	list, err := l.m.getNode(sc.DomainId, item.ParentId)
-	if err != nil {
-		return err
-	}
 	s.Set("List", list)
 	s.Set("Item", item)
 	if len(node.Title) == 0 {
 		node.Title = s.Lang("Re") + ": " + item.Title
 	}
 	s.Set("Form", node)
-	s.Set("Items", l.m.mustGetChildNodes(sc.DomainId, itemId, itemsPerPage, 0, "created DESC"))
+	items := l.m.mustGetChildNodesWithDeleted(sc.DomainId, itemId, itemsPerPage, 0, "created DESC")
+	s.Set("Items", items)
+	s.Set("Backlinks", l.m.mustGetBacklinks(sc.DomainId, append(items.Ids(), list.Id, item.Id)))
 	s.Set("FormTitle", s.Lang("New vote"))
 	s.AddPath("/", s.Lang("Home"))
 	s.AddPath("/list/"+strconv.Itoa(list.Id)+"/"+hfSlug(list.Title), list.Title)
@@ -339,9 +559,14 @@ func (l *ListBoard) feed(w http.ResponseWriter, sc *SiteConfig, baseURL string,
 		Author:      &feeds.Author{Name: sc.AuthorName, Email: sc.AuthorEmail},
 		Created:     time.Now(),
 	}
+	tr := l.tp.Get(sc.Language)
 	for _, node := range *nodes {
+		title := node.Title
+		if node.IsPinned() {
+			title = tr.Lang("Pinned") + ": " + title
+		}
 		feed.Items = append(feed.Items, &feeds.Item{
-			Title:       node.Title,
+			Title:       title,
 			Link:        &feeds.Link{Href: getUrl(baseURL, node)},
 			Description: string(node.Rendered),
 			Created:     node.Created,
@@ -353,14 +578,23 @@ func (l *ListBoard) feed(w http.ResponseWriter, sc *SiteConfig, baseURL string,
 
 func (l *ListBoard) feedHandler(w http.ResponseWriter, r *http.Request) error {
 	sc := l.config.getSiteConfig(l.getToken(r))
-	nodes := l.m.mustGetChildNodes(sc.DomainId, 0, 20, 0, "created DESC")
+	// Pinned lists lead the feed
+	nodes, err := l.m.getPinnedLists(sc.DomainId)
+	if err != nil {
+		return err
+	}
+	recent, err := l.m.getLists(sc.DomainId, IndexFilter{Sort: sortNewest, Unpinned: true}, feedSize, 0)
+	if err != nil {
+		return err
+	}
+	*nodes = append(*nodes, *recent...)
 	baseUrl := "http://" + r.Host
 	return l.feed(w, sc, baseUrl, nodes)
 }
 
 func (l *ListBoard) feedAllHandler(w http.ResponseWriter, r *http.Request) error {
 	sc := l.config.getSiteConfig(l.getToken(r))
-	nodes := l.m.mustGetAllNodes(sc.DomainId, 20, 0, "created DESC")
+	nodes := l.m.mustGetAllNodes(sc.DomainId, feedSize, 0, "created DESC")
 	baseUrl := "http://" + r.Host
 	return l.feed(w, sc, baseUrl, nodes)
 }
@@ -386,34 +620,92 @@ func (l *ListBoard) sitemapHandler(w http.ResponseWriter, r *http.Request) error
 	return err
 }
 
-func (l *ListBoard) validateForm(r *http.Request, domainId, parentId, level int, ln *Language) (Node, ValidationErrors) {
+func (l *ListBoard) validateForm(r *http.Request, sc *SiteConfig, parentId, level int, ln *Language) (Node, ValidationErrors) {
 	node := Node{
 		ParentId: parentId,
-		DomainId: domainId,
+		DomainId: sc.DomainId,
 		Title:    strings.TrimSpace(r.FormValue("title")),
 		Vote:     getVote(r.FormValue("vote")),
-		Tripcode: getTripcode(r.FormValue("password")),
+		Tripcode: l.config.tripcode(r.FormValue("password")),
 		Body:     r.FormValue("body"),
 		Status:   statusEnabled,
 		Level:    level,
 	}
 	errors := ValidationErrors{}
+	if level == levelRoot {
+		node.Ranking = validRanking(r.FormValue("ranking"))
+		node.Kind = validKind(r.FormValue("kind"))
+		if node.IsPoll() {
+			node.Options = parsePollOptions(r.FormValue("options"))
+		}
+		maxItems, _ := strconv.Atoi(r.FormValue("max_items"))
+		node.Settings = newListSettings(node.IsPoll() && r.FormValue("multiple") != "", r.FormValue("items"),
+			r.FormValue("voting"), r.FormValue("comments_only") != "", maxItems).encode()
+		node.Tags = parseTags(r.FormValue("tags"))
+		closes, closesErrors := parseCloses(r.FormValue("closes"), ln)
+		node.Closes = closes
+		errors = append(errors, closesErrors...)
+	}
+	if account := l.currentAccount(r, sc.DomainId); account != nil {
+		node.AccountId = account.Id
+		node.Username = account.Username
+	}
 	if !l.sg.CanPost(r.RemoteAddr) {
 		errors = append(errors, ln.Lang("Please wait before posting again"))
 	}
+	return node, append(errors, l.validateNode(&node, sc, ln)...)
+}
+
+// validateNode checks the node content and renders its body
+func (l *ListBoard) validateNode(node *Node, sc *SiteConfig, ln *Language) ValidationErrors {
+	return l.checkNode(node, sc, ln, l.renderNode)
+}
+
+// previewNode validates the node like validateNode without fetching the
+// link previews
+func (l *ListBoard) previewNode(node *Node, sc *SiteConfig, ln *Language) ValidationErrors {
+	return l.checkNode(node, sc, ln, l.renderBody)
+}
+
+func (l *ListBoard) checkNode(node *Node, sc *SiteConfig, ln *Language, render func(*SiteConfig, *Node) error) ValidationErrors {
+	errors := ValidationErrors{}
+	if node.Level == levelRoot {
+		errors = append(errors, validateTags(node.Tags, ln)...)
+		if node.IsPoll() {
+			errors = append(errors, validatePoll(node, ln)...)
+		}
+	}
 	if len(node.Title) < 3 {
 		errors = append(errors, ln.Lang("Title must be at least 3 characters long"))
 	}
 	if len(node.Body) < 10 {
 		errors = append(errors, ln.Lang("Please, write something"))
 	} else {
-		node.Rendered = renderText(node.Body)
+		if err := render(sc, node); err != nil {
+			log.Printf("Error resolving references: %s", err)
+			node.Rendered = sc.renderText(node.Body)
+		}
 		// Check again after the rendering
 		if len(node.Rendered) < 10 {
 			errors = append(errors, ln.Lang("Please, write something"))
 		}
 	}
-	return node, errors
+	return errors
+}
+
+// credentials returns the tripcodes of the posted password and the account of
+// the current visitor
+func (l *ListBoard) credentials(r *http.Request, domainId int) Credentials {
+	password := r.FormValue("password")
+	c := Credentials{
+		Tripcode:        l.config.tripcode(password),
+		ClassicTripcode: getTripcode(password),
+	}
+	if account := l.currentAccount(r, domainId); account != nil {
+		c.AccountId = account.Id
+		c.Username = account.Username
+	}
+	return c
 }
 
 func (l *ListBoard) getToken(r *http.Request) string {
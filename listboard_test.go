@@ -1,6 +1,11 @@
 package main
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -12,3 +17,56 @@ func TestNewListBoard(t *testing.T) {
 		}
 	})
 }
+
+func TestPreviewHandler(t *testing.T) {
+	fetcher := &countingFetcher{
+		stubFetcher: stubFetcher{"https://youtu.be/abc": []byte(testPage)},
+		fetches:     make(map[string]int),
+	}
+	l := &ListBoard{
+		config: &Config{Servers: map[string]SiteConfig{
+			"": {DomainId: 1, Previews: PreviewConfig{Enabled: true}},
+		}},
+		m:         newTestModel(t),
+		tp:        NewTransPool(t.TempDir() + "/"),
+		previewSg: NewSpamGuard("1h"),
+		fetcher:   fetcher,
+	}
+	preview := func(remoteAddr, title, body string) (int, previewResult) {
+		form := url.Values{"title": {title}, "body": {body}}
+		r := httptest.NewRequest("POST", "/preview", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		if err := l.previewHandler(w, r); err != nil {
+			t.Fatal(err)
+		}
+		var result previewResult
+		if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, result
+	}
+
+	t.Run("renders without fetching the previews", func(t *testing.T) {
+		code, result := preview("1.1.1.1:1", "Title", "Some *text* https://youtu.be/abc")
+		if code != http.StatusOK || len(result.Errors) != 0 || !strings.Contains(result.Rendered, "<em>text</em>") {
+			t.Errorf("previewHandler() = %d %+v", code, result)
+		}
+		if len(fetcher.fetches) != 0 {
+			t.Errorf("previewHandler() fetched %v", fetcher.fetches)
+		}
+	})
+	t.Run("too short after rendering", func(t *testing.T) {
+		code, result := preview("2.2.2.2:1", "Title", "<b></b><i></i><u></u>")
+		if code != http.StatusOK || len(result.Errors) != 1 || result.Errors[0] != "Please, write something" {
+			t.Errorf("previewHandler() = %d %+v, want one error", code, result)
+		}
+	})
+	t.Run("rate limited", func(t *testing.T) {
+		code, result := preview("1.1.1.1:2", "Title", "Some other text")
+		if code != http.StatusTooManyRequests || len(result.Errors) != 1 {
+			t.Errorf("previewHandler() = %d %+v, want the request blocked", code, result)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+const (
+	sanitizerUGC    = "ugc"
+	sanitizerStrict = "strict"
+)
+
+// defaultMarkdownExtensions match the features of the original renderer
+var defaultMarkdownExtensions = []string{"tables", "strikethrough", "linkify", "typographer"}
+
+var markdownExtensions = map[string]goldmark.Extender{
+	"tables":        extension.Table,
+	"tasklist":      extension.TaskList,
+	"footnotes":     extension.Footnote,
+	"strikethrough": extension.Strikethrough,
+	"linkify":       extension.Linkify,
+	"typographer":   extension.Typographer,
+}
+
+// MarkdownConfig selects the markdown extensions and the HTML sanitizer
+// policy of a site
+type MarkdownConfig struct {
+	Extensions []string `json:"extensions"`
+	Sanitizer  string   `json:"sanitizer"`
+}
+
+// Renderer converts the posted text to safe HTML
+type Renderer interface {
+	Render(text string) string
+}
+
+type markdownRenderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+}
+
+func NewMarkdownRenderer(mc MarkdownConfig) Renderer {
+	names := mc.Extensions
+	if names == nil {
+		names = defaultMarkdownExtensions
+	}
+	var extensions []goldmark.Extender
+	for _, name := range names {
+		ext, ok := markdownExtensions[name]
+		if !ok {
+			log.Printf("Unknown markdown extension %s", name)
+			continue
+		}
+		extensions = append(extensions, ext)
+	}
+	return &markdownRenderer{
+		md: goldmark.New(
+			goldmark.WithExtensions(extensions...),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+			goldmark.WithRendererOptions(html.WithHardWraps(), html.WithXHTML()),
+		),
+		policy: sanitizerPolicy(mc.Sanitizer),
+	}
+}
+
+func sanitizerPolicy(name string) *bluemonday.Policy {
+	switch name {
+	case sanitizerStrict:
+		return bluemonday.StrictPolicy()
+	case "", sanitizerUGC:
+		p := bluemonday.UGCPolicy()
+		// Task list check boxes
+		p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+		p.AllowAttrs("checked", "disabled").OnElements("input")
+		return p
+	default:
+		log.Printf("Unknown sanitizer policy %s, using %s", name, sanitizerUGC)
+		return sanitizerPolicy(sanitizerUGC)
+	}
+}
+
+func (mr *markdownRenderer) Render(text string) string {
+	var buf bytes.Buffer
+	if err := mr.md.Convert([]byte(text), &buf); err != nil {
+		log.Printf("Error rendering markdown: %s", err)
+		return ""
+	}
+	return string(mr.policy.SanitizeBytes(buf.Bytes()))
+}
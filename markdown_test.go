@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownRenderer(t *testing.T) {
+	tests := []struct {
+		name    string
+		mc      MarkdownConfig
+		text    string
+		want    string
+		notWant string
+	}{
+		{
+			name: "renders tables by default",
+			text: "| a | b |\n|---|---|\n| 1 | 2 |",
+			want: "<table>",
+		},
+		{
+			name:    "ignores disabled extensions",
+			mc:      MarkdownConfig{Extensions: []string{}},
+			text:    "~~gone~~",
+			notWant: "<del>",
+		},
+		{
+			name: "renders enabled task lists",
+			mc:   MarkdownConfig{Extensions: []string{"tasklist"}},
+			text: "- [x] done",
+			want: `type="checkbox"`,
+		},
+		{
+			name:    "sanitizes scripts",
+			text:    "hello <script>alert(1)</script>",
+			notWant: "<script>",
+		},
+		{
+			name:    "strict policy removes all markup",
+			mc:      MarkdownConfig{Sanitizer: sanitizerStrict},
+			text:    "**bold**",
+			notWant: "<strong>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewMarkdownRenderer(tt.mc).Render(tt.text)
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("Render() = %v, want %v", got, tt.want)
+			}
+			if tt.notWant != "" && strings.Contains(got, tt.notWant) {
+				t.Errorf("Render() = %v, should not contain %v", got, tt.notWant)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var listRefPattern = regexp.MustCompile(`^(?:.*/list/)?([0-9]+)(?:[/#?].*)?$`)
+
+// parseListRef reads a list id or the URL of a list
+func parseListRef(value string) int {
+	m := listRefPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0
+	}
+	id, _ := strconv.Atoi(m[1])
+	return id
+}
+
+// canModerate tells if the credentials may move the items of the lists.
+// Moderators may move any items, owners and co-editors only between the
+// lists they edit.
+func (l *ListBoard) canModerate(sc *SiteConfig, c Credentials, lists ...*Node) bool {
+	if sc.isModerator(c) {
+		return true
+	}
+	for _, list := range lists {
+		if !l.canEditList(list, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// moveErrors checks that the items of the list can be moved to the target
+func moveErrors(source, target *Node, ln *Language) ValidationErrors {
+	if target.Level != levelRoot {
+		return ValidationErrors{ln.Lang("Target list not found")}
+	}
+	if source.Id == target.Id {
+		return ValidationErrors{ln.Lang("Pick a different target list")}
+	}
+	if source.Kind != kindList || target.Kind != kindList {
+		return ValidationErrors{ln.Lang("Only items of regular lists can be moved")}
+	}
+	return nil
+}
+
+// moveVotes moves the vote total of the items from one list to another
+func moveVotes(tx *sqlx.Tx, domainId, from, to, votes int, now time.Time) error {
+	if _, err := tx.Exec("UPDATE node SET vote = vote - $1, updated = $2 WHERE domain_id = $3 AND id = $4", votes, now, domainId, from); err != nil {
+		return err
+	}
+	_, err := tx.Exec("UPDATE node SET vote = vote + $1, updated = $2 WHERE domain_id = $3 AND id = $4", votes, now, domainId, to)
+	return err
+}
+
+// moveItem moves the item with its votes to the target list and keeps the
+// vote totals of both lists
+func (m *Model) moveItem(item, target *Node) error {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+	if err := moveItem(tx, item, target); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// moveItem moves the item and its votes in the transaction
+func moveItem(tx *sqlx.Tx, item, target *Node) error {
+	var votes int
+	if err := tx.Get(&votes, "SELECT COUNT(*) FROM node WHERE parent_id = $1 AND level = $2 AND status = 1", item.Id, levelVote); err != nil {
+		return err
+	}
+	now := time.Now()
+	if _, err := tx.Exec("UPDATE node SET parent_id = $1, updated = $2 WHERE domain_id = $3 AND id = $4", target.Id, now, item.DomainId, item.Id); err != nil {
+		return err
+	}
+	return moveVotes(tx, item.DomainId, item.ParentId, target.Id, votes, now)
+}
+
+// mergeLists moves all items of the source list to the target, adds up
+// their vote totals and redirects the source list to the target
+func (m *Model) mergeLists(source, target *Node) error {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+	var votes int
+	if err := tx.Get(&votes, "SELECT vote FROM node WHERE domain_id = $1 AND id = $2", source.DomainId, source.Id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	queries := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE node SET parent_id = $1, updated = $2 WHERE domain_id = $3 AND parent_id = $4 AND level = $5",
+			[]interface{}{target.Id, now, source.DomainId, source.Id, levelList}},
+		{"UPDATE node SET status = $1 WHERE domain_id = $2 AND id = $3",
+			[]interface{}{statusMerged, source.DomainId, source.Id}},
+		{"INSERT INTO node_redirect (node_id, domain_id, target_id, created) VALUES ($1, $2, $3, $4)",
+			[]interface{}{source.Id, source.DomainId, target.Id, now}},
+		// Lists merged into the source earlier follow it
+		{"UPDATE node_redirect SET target_id = $1 WHERE domain_id = $2 AND target_id = $3",
+			[]interface{}{target.Id, source.DomainId, source.Id}},
+	}
+	for _, q := range queries {
+		if _, err := tx.Exec(q.query, q.args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := moveVotes(tx, source.DomainId, source.Id, target.Id, votes, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// splitList creates the new list and moves the items into it in a single
+// transaction
+func (m *Model) splitList(list *Node, items []*Node) (int, error) {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	id, err := insertNode(tx, list)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	list.Id = id
+	for _, item := range items {
+		if err := moveItem(tx, item, list); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// getRedirect returns the list the node was merged into
+func (m *Model) getRedirect(domainId, id int) (int, error) {
+	var targetId int
+	err := m.db.Get(&targetId, "SELECT target_id FROM node_redirect WHERE domain_id = $1 AND node_id = $2", domainId, id)
+	return targetId, err
+}
+
+// redirectMerged sends the visitors of a merged list to the list it was
+// merged into. It returns false when the list was not merged.
+func (l *ListBoard) redirectMerged(w http.ResponseWriter, r *http.Request, sc *SiteConfig, id int, prefix, suffix string) bool {
+	targetId, err := l.m.getRedirect(sc.DomainId, id)
+	if err != nil {
+		return false
+	}
+	http.Redirect(w, r, prefix+strconv.Itoa(targetId)+suffix, http.StatusMovedPermanently)
+	return true
+}
+
+// moveFormHandler moves an item to another list or merges a list into
+// another one
+func (l *ListBoard) moveFormHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+
+	var errors ValidationErrors
+	nodeId, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		return err
+	}
+	node, err := l.m.getNode(sc.DomainId, nodeId)
+	if err != nil {
+		return err
+	}
+	source := node
+	switch node.Level {
+	case levelRoot:
+	case levelList:
+		if source, err = l.m.getNode(sc.DomainId, node.ParentId); err != nil {
+			return err
+		}
+	default:
+		return HTTPError{Message: "Only lists and items can be moved", Code: http.StatusNotFound}
+	}
+
+	tr := l.tp.Get(sc.Language)
+	if r.Method == "POST" && !inHoneypot(r.FormValue("name")) {
+		target, err := l.m.getNode(sc.DomainId, parseListRef(r.FormValue("target")))
+		if err == sql.ErrNoRows {
+			target = &Node{}
+		} else if err != nil {
+			return err
+		}
+		errors = moveErrors(source, target, tr)
+		if len(errors) == 0 && !l.canModerate(sc, l.credentials(r, sc.DomainId), source, target) {
+			errors = append(errors, tr.Lang("Only moderators and the editors of both lists can move items"))
+		}
+		if len(errors) == 0 {
+			if node.Level == levelRoot {
+				err = l.m.mergeLists(node, target)
+			} else {
+				err = l.m.moveItem(node, target)
+			}
+			if err != nil {
+				return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+			}
+			http.Redirect(w, r, getUrl("", *target), http.StatusFound)
+			return nil
+		}
+	}
+
+	title := tr.Lang("Move item")
+	if node.Level == levelRoot {
+		title = tr.Lang("Merge list")
+	}
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Item", node)
+	s.Set("Target", r.FormValue("target"))
+	s.Set("Action", title)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath(getUrl("", *source), source.Title)
+	s.AddPath("", title)
+	s.Set("Subtitle", title)
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("move.html"))
+}
+
+// splitFormHandler moves the picked items of a list to a new list
+func (l *ListBoard) splitFormHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+
+	var errors ValidationErrors
+	listId, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		return err
+	}
+	source, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	if source.Level != levelRoot {
+		return HTTPError{Message: "Only lists can be split", Code: http.StatusNotFound}
+	}
+	total, err := l.m.getTotal(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	// All items are offered, not only the first page
+	items, err := l.m.getChildNodes(sc.DomainId, listId, total, 0, rankingOrder(source.Ranking, sc.Ranking))
+	if err != nil {
+		return err
+	}
+
+	tr := l.tp.Get(sc.Language)
+	// The new list keeps the author of the split one
+	list := Node{
+		DomainId:  sc.DomainId,
+		Title:     strings.TrimSpace(r.FormValue("title")),
+		Body:      r.FormValue("body"),
+		Tripcode:  source.Tripcode,
+		AccountId: source.AccountId,
+		Username:  source.Username,
+		Ranking:   source.Ranking,
+		Status:    statusEnabled,
+		Level:     levelRoot,
+	}
+	picked := map[int]bool{}
+	if r.Method == "POST" && !inHoneypot(r.FormValue("name")) {
+		var moved []*Node
+		for _, value := range r.Form["items"] {
+			id, _ := strconv.Atoi(value)
+			for i := range *items {
+				if (*items)[i].Id == id && !picked[id] {
+					picked[id] = true
+					moved = append(moved, &(*items)[i])
+				}
+			}
+		}
+		if source.Kind != kindList {
+			errors = append(errors, tr.Lang("Only items of regular lists can be moved"))
+		}
+		if len(moved) == 0 {
+			errors = append(errors, tr.Lang("Pick the items of the new list"))
+		}
+		if !l.canModerate(sc, l.credentials(r, sc.DomainId), source) {
+			errors = append(errors, tr.Lang("Only moderators and the editors of both lists can move items"))
+		}
+		errors = append(errors, l.validateNode(&list, sc, tr)...)
+		if len(errors) == 0 {
+			id, err := l.m.splitList(&list, moved)
+			if err != nil {
+				return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+			}
+			http.Redirect(w, r, "/list/"+strconv.Itoa(id)+"/"+hfSlug(list.Title), http.StatusFound)
+			return nil
+		}
+	} else {
+		list.Body = tr.Lang("Split from") + " >>" + strconv.Itoa(source.Id)
+	}
+
+	title := tr.Lang("Split list")
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Item", source)
+	s.Set("Items", items)
+	s.Set("Picked", picked)
+	s.Set("Form", list)
+	s.Set("Action", title)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath(getUrl("", *source), source.Title)
+	s.AddPath("", title)
+	s.Set("Subtitle", title)
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("split.html"))
+}
@@ -0,0 +1,142 @@
+package main
+
+import "testing"
+
+func TestParseListRef(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"12", 12},
+		{" 12 ", 12},
+		{"http://example.com/list/34/some-list.html", 34},
+		{"/list/34/some-list.html#I56", 34},
+		{"/vote/34/item.html", 0},
+		{"list", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := parseListRef(tt.value); got != tt.want {
+			t.Errorf("parseListRef(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCanModerate(t *testing.T) {
+	l := &ListBoard{m: newTestModel(t)}
+	sc := &SiteConfig{DomainId: 1, Moderators: []Moderator{{Username: "mod"}, {Tripcode: "!tripmod"}}}
+	own := &Node{Id: 1, DomainId: 1, AccountId: 1}
+	other := &Node{Id: 2, DomainId: 1, Tripcode: "!other"}
+	if err := l.m.addEditor(&ListEditor{ListId: 2, DomainId: 1, Tripcode: "!editor"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		c     Credentials
+		lists []*Node
+		want  bool
+	}{
+		{"moderator account", Credentials{AccountId: 3, Username: "mod"}, []*Node{own, other}, true},
+		{"moderator tripcode", Credentials{Tripcode: "!tripmod"}, []*Node{other}, true},
+		{"account named like a moderator tripcode", Credentials{AccountId: 4, Username: "!tripmod"}, []*Node{other}, false},
+		{"tripcode named like a moderator account", Credentials{Tripcode: "mod"}, []*Node{other}, false},
+		{"author of both", Credentials{AccountId: 1}, []*Node{own, own}, true},
+		{"author of one", Credentials{AccountId: 1}, []*Node{own, other}, false},
+		{"editor", Credentials{Tripcode: "!editor"}, []*Node{other}, true},
+		{"editor of one", Credentials{Tripcode: "!editor"}, []*Node{own, other}, false},
+		{"anonymous", Credentials{}, []*Node{{}}, false},
+	}
+	for _, tt := range tests {
+		if got := l.canModerate(sc, tt.c, tt.lists...); got != tt.want {
+			t.Errorf("%s: canModerate() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// addVotedItem adds an item with the votes to the list
+func addVotedItem(t *testing.T, m *Model, listId int, votes ...int) *Node {
+	id, err := m.addNode(&Node{DomainId: 1, ParentId: listId, Title: "item", Status: statusEnabled, Level: levelList})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, vote := range votes {
+		voteId, _ := m.addNode(&Node{DomainId: 1, ParentId: id, Title: "vote", Vote: vote, Status: statusEnabled, Level: levelVote})
+		if err := m.Vote(1, vote, voteId, id, listId); err != nil {
+			t.Fatal(err)
+		}
+	}
+	item, _ := m.getNode(1, id)
+	return item
+}
+
+func TestModelMoveItem(t *testing.T) {
+	m := newTestModel(t)
+	source, _ := m.addNode(&Node{DomainId: 1, Title: "source", Status: statusEnabled, Level: levelRoot})
+	target, _ := m.addNode(&Node{DomainId: 1, Title: "target", Status: statusEnabled, Level: levelRoot})
+	item := addVotedItem(t, m, source, 1, 1, -1)
+	addVotedItem(t, m, source, 1)
+	targetList, _ := m.getNode(1, target)
+	if err := m.moveItem(item, targetList); err != nil {
+		t.Fatal(err)
+	}
+	moved, _ := m.getNode(1, item.Id)
+	if moved.ParentId != target || moved.Vote != 1 {
+		t.Errorf("moveItem() item parent = %d, vote = %d, want %d, 1", moved.ParentId, moved.Vote, target)
+	}
+	sourceList, _ := m.getNode(1, source)
+	targetList, _ = m.getNode(1, target)
+	if sourceList.Vote != 1 || targetList.Vote != 3 {
+		t.Errorf("moveItem() list votes = %d, %d, want 1, 3", sourceList.Vote, targetList.Vote)
+	}
+}
+
+func TestModelMergeLists(t *testing.T) {
+	m := newTestModel(t)
+	var ids []int
+	for _, title := range []string{"first", "second", "third"} {
+		id, _ := m.addNode(&Node{DomainId: 1, Title: title, Status: statusEnabled, Level: levelRoot})
+		ids = append(ids, id)
+	}
+	addVotedItem(t, m, ids[0], 1, -1)
+	addVotedItem(t, m, ids[1], 1)
+	for i := 0; i < 2; i++ {
+		source, _ := m.getNode(1, ids[i])
+		target, _ := m.getNode(1, ids[i+1])
+		if err := m.mergeLists(source, target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, _ := m.getChildNodes(1, ids[2], 10, 0, "id")
+	list, _ := m.getNode(1, ids[2])
+	if len(*items) != 2 || list.Vote != 3 {
+		t.Errorf("mergeLists() items = %d, vote = %d, want 2, 3", len(*items), list.Vote)
+	}
+	for _, id := range ids[:2] {
+		if targetId, err := m.getRedirect(1, id); err != nil || targetId != ids[2] {
+			t.Errorf("getRedirect(%d) = %d, %v, want %d", id, targetId, err, ids[2])
+		}
+	}
+}
+
+func TestModelSplitList(t *testing.T) {
+	m := newTestModel(t)
+	source, _ := m.addNode(&Node{DomainId: 1, Title: "source", Status: statusEnabled, Level: levelRoot})
+	moved := addVotedItem(t, m, source, 1, 1)
+	addVotedItem(t, m, source, 1)
+	list := &Node{DomainId: 1, Title: "split", Body: "Split from >>1", References: []int{source}, Status: statusEnabled, Level: levelRoot}
+	id, err := m.splitList(list, []*Node{moved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := m.getTotal(1, id); total != 1 {
+		t.Errorf("splitList() moved %d items, want 1", total)
+	}
+	sourceList, _ := m.getNode(1, source)
+	newList, _ := m.getNode(1, id)
+	if sourceList.Vote != 1 || newList.Vote != 2 {
+		t.Errorf("splitList() list votes = %d, %d, want 1, 2", sourceList.Vote, newList.Vote)
+	}
+	if bl, _ := m.getBacklinks(1, []int{source}); len(bl[source]) != 1 {
+		t.Errorf("splitList() backlinks = %v, want the new list", bl)
+	}
+}
@@ -6,9 +6,16 @@ import (
 	"strconv"
 )
 
+// paginationWindow is the number of pages linked on each side of the
+// current one
+const paginationWindow = 3
+
 type Page struct {
 	Num int
 	URL string
+	// Label replaces the number of the first, previous, next and last page
+	// links and marks the skipped pages
+	Label string
 }
 
 type Pages []Page
@@ -26,24 +33,54 @@ func Pagination(pc PaginationConfig) Pages {
 	if pc.total <= pc.ipp {
 		return make(Pages, 0)
 	}
-	pages := make(Pages, pCount)
-	// Normalize first page
-	if pc.page == 0 {
+	// Keep the page in range, it comes from the request
+	if pc.page < 1 {
 		pc.page = 1
 	}
+	if pc.page > pCount {
+		pc.page = pCount
+	}
 	pUrl, _ := url.Parse(pc.url)
 	val := pUrl.Query()
-	// Number of pages
-
-	for i := 1; i <= pCount; i++ {
+	pageURL := func(i int) string {
 		// Don't set the url for the current page
-		tURL := ""
-		if i != pc.page {
-			val.Set(pc.param, strconv.Itoa(i))
-			pUrl.RawQuery = val.Encode()
-			tURL = pUrl.String()
+		if i == pc.page {
+			return ""
 		}
-		pages[i-1] = Page{i, tURL}
+		val.Set(pc.param, strconv.Itoa(i))
+		pUrl.RawQuery = val.Encode()
+		return pUrl.String()
+	}
+
+	first := pc.page - paginationWindow
+	if first < 1 {
+		first = 1
+	}
+	last := pc.page + paginationWindow
+	if last > pCount {
+		last = pCount
+	}
+	pages := make(Pages, 0, last-first+7)
+	if pc.page > 1 {
+		pages = append(pages, Page{pc.page - 1, pageURL(pc.page - 1), "Previous"})
+	}
+	if first > 1 {
+		pages = append(pages, Page{1, pageURL(1), "First"})
+		if first > 2 {
+			pages = append(pages, Page{Label: "..."})
+		}
+	}
+	for i := first; i <= last; i++ {
+		pages = append(pages, Page{i, pageURL(i), ""})
+	}
+	if last < pCount {
+		if last < pCount-1 {
+			pages = append(pages, Page{Label: "..."})
+		}
+		pages = append(pages, Page{pCount, pageURL(pCount), "Last"})
+	}
+	if pc.page < pCount {
+		pages = append(pages, Page{pc.page + 1, pageURL(pc.page + 1), "Next"})
 	}
 	return pages
 }
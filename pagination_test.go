@@ -39,8 +39,64 @@ func TestPagination(t *testing.T) {
 				param: "page",
 			},
 			want: Pages{
-				Page{1, ""},
-				Page{2, "http://example.com?page=2"},
+				Page{1, "", ""},
+				Page{2, "http://example.com?page=2", ""},
+				Page{2, "http://example.com?page=2", "Next"},
+			},
+		},
+		{
+			name: "returns a window around the current page",
+			pc: PaginationConfig{
+				ipp:   10,
+				page:  6,
+				total: 200,
+				url:   "?",
+				param: "page",
+			},
+			want: Pages{
+				Page{5, "?page=5", "Previous"},
+				Page{1, "?page=1", "First"},
+				Page{0, "", "..."},
+				Page{3, "?page=3", ""},
+				Page{4, "?page=4", ""},
+				Page{5, "?page=5", ""},
+				Page{6, "", ""},
+				Page{7, "?page=7", ""},
+				Page{8, "?page=8", ""},
+				Page{9, "?page=9", ""},
+				Page{0, "", "..."},
+				Page{20, "?page=20", "Last"},
+				Page{7, "?page=7", "Next"},
+			},
+		},
+		{
+			name: "keeps a page after the last one in range",
+			pc: PaginationConfig{
+				ipp:   10,
+				page:  999,
+				total: 13,
+				url:   "?",
+				param: "page",
+			},
+			want: Pages{
+				Page{1, "?page=1", "Previous"},
+				Page{1, "?page=1", ""},
+				Page{2, "", ""},
+			},
+		},
+		{
+			name: "keeps a negative page in range",
+			pc: PaginationConfig{
+				ipp:   10,
+				page:  -5,
+				total: 13,
+				url:   "?",
+				param: "page",
+			},
+			want: Pages{
+				Page{1, "", ""},
+				Page{2, "?page=2", ""},
+				Page{2, "?page=2", "Next"},
 			},
 		},
 	}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxPosition is the last position of the pinned and featured sections
+const maxPosition = 100
+
+// pinnedItemsOrder puts the pinned items of a list in front of the others
+const pinnedItemsOrder = "pinned = 0, pinned, "
+
+// pinnedCondition matches the lists with a pin which has not expired. The
+// expiry times are stored in UTC so they compare with pinNow.
+const pinnedCondition = "pinned > 0 AND (pin_expires IS NULL OR pin_expires > ?)"
+
+// pinNow returns the current time as stored in the pin expiry times
+func pinNow() time.Time {
+	return time.Now().UTC()
+}
+
+// IsPinned tells if the list is pinned and its pin has not expired
+func (n Node) IsPinned() bool {
+	return n.Pinned > 0 && (n.PinExpires == nil || n.PinExpires.After(time.Now()))
+}
+
+// IsFeatured tells if the list is in the featured section
+func (n Node) IsFeatured() bool {
+	return n.Featured > 0
+}
+
+// PinExpiresInput returns the pin expiry in the format of the form input
+func (n Node) PinExpiresInput() string {
+	if n.PinExpires == nil {
+		return ""
+	}
+	return n.PinExpires.Local().Format(closesFormat)
+}
+
+// validPosition limits a position in the pinned or featured section, 0
+// takes the list out of the section
+func validPosition(position int) int {
+	if position < 0 {
+		return 0
+	}
+	if position > maxPosition {
+		return maxPosition
+	}
+	return position
+}
+
+// parsePosition reads a position from the form
+func parsePosition(value string) int {
+	position, _ := strconv.Atoi(value)
+	return validPosition(position)
+}
+
+// getPinnedLists returns the lists with a pin which has not expired in their
+// order
+func (m *Model) getPinnedLists(domainId int) (*NodeList, error) {
+	var nl NodeList
+	err := m.db.Select(&nl, "SELECT * FROM node WHERE domain_id = ? AND level = ? AND status = 1 AND "+pinnedCondition+" ORDER BY pinned, id DESC",
+		domainId, levelRoot, pinNow())
+	return &nl, err
+}
+
+// getFeaturedLists returns the featured lists in their order
+func (m *Model) getFeaturedLists(domainId int) (*NodeList, error) {
+	var nl NodeList
+	err := m.db.Select(&nl, "SELECT * FROM node WHERE domain_id = $1 AND level = $2 AND status = 1 AND featured > 0 ORDER BY featured, id DESC", domainId, levelRoot)
+	return &nl, err
+}
+
+// setPin sets the positions of the list in the pinned and featured sections
+// or the position of the item among the pinned items of its list
+func (m *Model) setPin(node *Node, pinned int, expires *time.Time, featured int) error {
+	if pinned == 0 {
+		expires = nil
+	} else if expires != nil {
+		utc := expires.UTC()
+		expires = &utc
+	}
+	_, err := m.db.NamedExec(`UPDATE node SET
+			pinned = :pinned,
+			pin_expires = :pin_expires,
+			featured = :featured
+			WHERE id = :id
+			AND domain_id = :domain_id`,
+		map[string]interface{}{
+			"pinned":      pinned,
+			"pin_expires": expires,
+			"featured":    featured,
+			"id":          node.Id,
+			"domain_id":   node.DomainId,
+		})
+	return err
+}
+
+// canPin tells if the credentials may pin the node. Moderators pin lists on
+// the index, the editors of a list pin its items.
+func (l *ListBoard) canPin(sc *SiteConfig, node *Node, c Credentials) bool {
+	if node.Level == levelRoot {
+		return sc.isModerator(c)
+	}
+	list, err := l.m.getNode(node.DomainId, node.ParentId)
+	return err == nil && l.canModerate(sc, c, list)
+}
+
+// pinErrors explains who may pin the node
+func pinErrors(node *Node, ln *Language) ValidationErrors {
+	if node.Level == levelRoot {
+		return ValidationErrors{ln.Lang("Only moderators can pin lists")}
+	}
+	return ValidationErrors{ln.Lang("Only the editors of the list can pin its items")}
+}
+
+// setPinnedSession adds the pinned and featured lists to the session
+func (l *ListBoard) setPinnedSession(s *Session, sc *SiteConfig) error {
+	pinned, err := l.m.getPinnedLists(sc.DomainId)
+	if err != nil {
+		return err
+	}
+	featured, err := l.m.getFeaturedLists(sc.DomainId)
+	if err != nil {
+		return err
+	}
+	s.Set("Pinned", pinned)
+	s.Set("Featured", featured)
+	s.Set("PinnedTags", l.m.mustGetTags(sc.DomainId, pinned.Ids()))
+	s.Set("PinnedProgress", l.m.mustGetProgress(sc.DomainId, pinned))
+	return nil
+}
+
+func (l *ListBoard) pinFormHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+
+	var errors ValidationErrors
+	listId, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		return err
+	}
+	node, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	if node.Level != levelRoot && node.Level != levelList {
+		return HTTPError{Message: "Only lists and items can be pinned", Code: http.StatusNotFound}
+	}
+
+	tr := l.tp.Get(sc.Language)
+	if r.Method == "POST" && !inHoneypot(r.FormValue("name")) {
+		node.Pinned = parsePosition(r.FormValue("pinned"))
+		if node.Level == levelRoot {
+			node.Featured = parsePosition(r.FormValue("featured"))
+			expires, err := parseLocalTime(r.FormValue("pin_expires"))
+			if err != nil {
+				errors = append(errors, tr.Lang("Invalid expiry time"))
+			}
+			node.PinExpires = expires
+		}
+		if !l.canPin(sc, node, l.credentials(r, sc.DomainId)) {
+			errors = append(errors, pinErrors(node, tr)...)
+		}
+		if len(errors) == 0 {
+			if err := l.m.setPin(node, node.Pinned, node.PinExpires, node.Featured); err != nil {
+				return &HTTPError{Err: err, Code: http.StatusInternalServerError}
+			}
+			url := "/"
+			if node.Level == levelList {
+				url = getUrl("", *node)
+			}
+			http.Redirect(w, r, url, http.StatusFound)
+			return nil
+		}
+	}
+
+	title := tr.Lang("Pin list")
+	if node.Level == levelList {
+		title = tr.Lang("Pin item")
+	}
+	s := l.session(w, r, sc, tr)
+	s.Set("Errors", errors)
+	s.Set("Item", node)
+	s.Set("Action", title)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath(getUrl("", *node), node.Title)
+	s.AddPath("", title)
+	s.Set("Subtitle", title)
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("pin.html"))
+}
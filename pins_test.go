@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNodeIsPinned(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name string
+		node Node
+		want bool
+	}{
+		{"not pinned", Node{}, false},
+		{"pinned", Node{Pinned: 1}, true},
+		{"pinned until later", Node{Pinned: 2, PinExpires: &future}, true},
+		{"expired pin", Node{Pinned: 1, PinExpires: &past}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.node.IsPinned(); got != tt.want {
+			t.Errorf("%s: IsPinned() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	for value, want := range map[string]int{"": 0, "x": 0, "-3": 0, "2": 2, "1000": maxPosition} {
+		if got := parsePosition(value); got != want {
+			t.Errorf("parsePosition(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestModelPinnedLists(t *testing.T) {
+	m := newTestModel(t)
+	// The wall time of the expired pin is ahead of UTC
+	past := time.Now().Add(-time.Hour).In(time.FixedZone("east", 14*60*60))
+	var ids []int
+	for _, title := range []string{"first", "second", "third", "fourth"} {
+		id, _ := m.addNode(&Node{DomainId: 1, Title: title, Status: statusEnabled, Level: levelRoot})
+		ids = append(ids, id)
+	}
+	m.setPin(&Node{Id: ids[0], DomainId: 1}, 2, nil, 0)
+	m.setPin(&Node{Id: ids[1], DomainId: 1}, 1, nil, 1)
+	m.setPin(&Node{Id: ids[2], DomainId: 1}, 1, &past, 0)
+
+	pinned, err := m.getPinnedLists(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pinned.Ids(); !reflect.DeepEqual(got, []int{ids[1], ids[0]}) {
+		t.Errorf("getPinnedLists() = %v, want %v", got, []int{ids[1], ids[0]})
+	}
+	featured, _ := m.getFeaturedLists(1)
+	if got := featured.Ids(); !reflect.DeepEqual(got, []int{ids[1]}) {
+		t.Errorf("getFeaturedLists() = %v, want %v", got, []int{ids[1]})
+	}
+	// The list with the expired pin is back with the others
+	lists, _ := m.getLists(1, IndexFilter{Sort: sortNewest, Unpinned: true}, 10, 0)
+	if got := lists.Ids(); !reflect.DeepEqual(got, []int{ids[3], ids[2]}) {
+		t.Errorf("getLists() = %v, want %v", got, []int{ids[3], ids[2]})
+	}
+	// Reading the lists doesn't write anything
+	if expired, _ := m.getNode(1, ids[2]); expired.Pinned != 1 {
+		t.Errorf("expired list pinned = %d, want it left as it was", expired.Pinned)
+	}
+}
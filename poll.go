@@ -0,0 +1,236 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const maxPollOptionLength = 150
+
+// PollResult is the tally of a single poll option
+type PollResult struct {
+	Item    Node
+	Votes   int
+	Percent int
+}
+
+// parsePollOptions returns the unique non-empty lines of text
+func parsePollOptions(text string) []string {
+	var options []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		option := strings.TrimSpace(line)
+		if option == "" || seen[option] {
+			continue
+		}
+		seen[option] = true
+		options = append(options, option)
+	}
+	return options
+}
+
+// validatePoll checks the options of a new poll
+func validatePoll(node *Node, ln *Language) ValidationErrors {
+	errors := ValidationErrors{}
+	if len(node.Options) < 2 {
+		errors = append(errors, ln.Lang("A poll needs at least two options"))
+	}
+	for _, option := range node.Options {
+		if len(option) > maxPollOptionLength {
+			errors = append(errors, ln.Lang("Poll options must be shorter than 150 characters"))
+			break
+		}
+	}
+	return errors
+}
+
+// pollResults tallies the options. Percentages are of the voters so they
+// don't add up to 100 in multiple choice polls.
+func pollResults(options NodeList, voters int) []PollResult {
+	results := make([]PollResult, len(options))
+	for i, option := range options {
+		results[i] = PollResult{Item: option, Votes: option.Up}
+		if voters > 0 {
+			results[i].Percent = (option.Up*100 + voters/2) / voters
+		}
+	}
+	return results
+}
+
+// pickPollOptions returns the chosen option ids that belong to the poll
+func pickPollOptions(options NodeList, chosen []string, multiple bool, ln *Language) ([]int, ValidationErrors) {
+	valid := make(map[int]bool)
+	for _, option := range options {
+		valid[option.Id] = true
+	}
+	var ids []int
+	for _, value := range chosen {
+		id, err := strconv.Atoi(value)
+		if err != nil || !valid[id] {
+			return nil, ValidationErrors{ln.Lang("Unknown poll option")}
+		}
+		valid[id] = false
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, ValidationErrors{ln.Lang("Please, pick an option")}
+	}
+	if len(ids) > 1 && !multiple {
+		return nil, ValidationErrors{ln.Lang("Please, pick only one option")}
+	}
+	return ids, nil
+}
+
+// voterKey identifies the visitor voting in a poll or a ranked choice list
+func (l *ListBoard) voterKey(r *http.Request, domainId int) string {
+	accountId := 0
+	if account := l.currentAccount(r, domainId); account != nil {
+		accountId = account.Id
+	}
+	return voterKey(r, accountId)
+}
+
+// voterKey identifies the owner of the token voting in a poll or a ranked
+// choice list
+func (t *ApiToken) voterKey(r *http.Request) string {
+	return voterKey(r, t.AccountId)
+}
+
+// voterKey identifies voters by account or else by a hash of the address.
+// Tripcodes are free to make, so a new password doesn't give another vote.
+func voterKey(r *http.Request, accountId int) string {
+	if accountId != 0 {
+		return "account:" + strconv.Itoa(accountId)
+	}
+	return "ip:" + hashToken(remoteHost(r))
+}
+
+// titledItems returns a new item for each title
+func titledItems(titles []string) []Node {
+	items := make([]Node, len(titles))
+	for i, title := range titles {
+		items[i] = Node{Title: title}
+	}
+	return items
+}
+
+func (m *Model) hasPollVote(listId int, voter string) (bool, error) {
+	var count int
+	err := m.db.Get(&count, "SELECT COUNT(*) FROM poll_vote WHERE list_id = $1 AND voter = $2", listId, voter)
+	return count > 0, err
+}
+
+func (m *Model) getPollVoters(listId int) (int, error) {
+	var count int
+	err := m.db.Get(&count, "SELECT COUNT(DISTINCT voter) FROM poll_vote WHERE list_id = $1", listId)
+	return count, err
+}
+
+// addPollVotes records the choices of the voter and counts them as up votes
+// of the options. The voter is recorded once per poll in the same
+// transaction, so concurrent votes of a voter can't both be counted.
+func (m *Model) addPollVotes(domainId, listId int, itemIds []int, voter string) error {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+	if err := addPollVotes(tx, domainId, listId, itemIds, voter); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func addPollVotes(tx *sqlx.Tx, domainId, listId int, itemIds []int, voter string) error {
+	now := time.Now()
+	res, err := tx.Exec("INSERT OR IGNORE INTO poll_voter (list_id, voter, created) VALUES ($1, $2, $3)", listId, voter, now)
+	if err != nil {
+		return err
+	}
+	if added, err := res.RowsAffected(); err != nil || added == 0 {
+		if err == nil {
+			err = errAlreadyVoted
+		}
+		return err
+	}
+	for _, itemId := range itemIds {
+		_, err := tx.Exec("INSERT INTO poll_vote (list_id, item_id, voter, created) VALUES ($1, $2, $3, $4)",
+			listId, itemId, voter, now)
+		if err != nil {
+			return err
+		}
+		if err := countVote(tx, domainId, itemId, 1, 1); err != nil {
+			return err
+		}
+		if err := bumpVote(tx, domainId, listId, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// votePoll validates and stores the choices of a voter. Identified voters
+// have a tripcode or an account.
+func (l *ListBoard) votePoll(list *Node, chosen []string, voter string, identified bool, ln *Language) ValidationErrors {
+	if errors := closedErrors(list, ln); len(errors) != 0 {
+		return errors
+	}
+	if errors := votingErrors(list, identified, ln); len(errors) != 0 {
+		return errors
+	}
+	options, err := l.m.getChildNodes(list.DomainId, list.Id, itemsPerPage, 0, "id")
+	if err != nil {
+		return ValidationErrors{err.Error()}
+	}
+	ids, errors := pickPollOptions(*options, chosen, list.ListSettings().Multiple, ln)
+	if len(errors) != 0 {
+		return errors
+	}
+	if err := l.m.addPollVotes(list.DomainId, list.Id, ids, voter); err == errAlreadyVoted {
+		return ValidationErrors{ln.Lang("You have already voted in this poll")}
+	} else if err != nil {
+		return ValidationErrors{err.Error()}
+	}
+	return nil
+}
+
+// pollHandler shows the options and the results of a poll list
+func (l *ListBoard) pollHandler(w http.ResponseWriter, r *http.Request, sc *SiteConfig, ln *Language, list *Node) error {
+	var errors ValidationErrors
+	voter := l.voterKey(r, sc.DomainId)
+	if r.Method == "POST" && !inHoneypot(r.FormValue("name")) {
+		r.ParseForm()
+		errors = l.votePoll(list, r.Form["option"], voter, l.credentials(r, sc.DomainId).identified(), ln)
+		if len(errors) == 0 {
+			http.Redirect(w, r, r.URL.String(), http.StatusFound)
+			return nil
+		}
+	}
+	options := l.m.mustGetChildNodes(sc.DomainId, list.Id, itemsPerPage, 0, "id")
+	voters, err := l.m.getPollVoters(list.Id)
+	if err != nil {
+		return err
+	}
+	voted, err := l.m.hasPollVote(list.Id, voter)
+	if err != nil {
+		return err
+	}
+	s := l.session(w, r, sc, ln)
+	s.Set("Errors", errors)
+	s.Set("List", list)
+	s.Set("ListTags", l.m.mustGetTags(sc.DomainId, []int{list.Id}))
+	s.Set("Multiple", list.ListSettings().Multiple)
+	s.Set("Results", pollResults(*options, voters))
+	s.Set("Voters", voters)
+	s.Set("Voted", voted)
+	s.Set("Backlinks", l.m.mustGetBacklinks(sc.DomainId, []int{list.Id}))
+	s.Set("Subtitle", list.Title)
+	s.Set("Description", list.Title)
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath("", list.Title)
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("poll.html"))
+}
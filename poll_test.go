@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParsePollOptions(t *testing.T) {
+	got := parsePollOptions("Pizza\n  Sushi \r\n\nPizza\nTacos\n")
+	want := []string{"Pizza", "Sushi", "Tacos"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePollOptions() = %v, want %v", got, want)
+	}
+}
+
+func TestPollResults(t *testing.T) {
+	options := NodeList{{Id: 1, Up: 2}, {Id: 2, Up: 1}, {Id: 3}}
+	got := pollResults(options, 3)
+	percents := []int{got[0].Percent, got[1].Percent, got[2].Percent}
+	if !reflect.DeepEqual(percents, []int{67, 33, 0}) {
+		t.Errorf("pollResults() percents = %v, want [67 33 0]", percents)
+	}
+	if got := pollResults(options, 0); got[0].Percent != 0 {
+		t.Errorf("pollResults() without voters = %v, want 0", got[0].Percent)
+	}
+}
+
+func TestPickPollOptions(t *testing.T) {
+	ln := &Language{}
+	options := NodeList{{Id: 1}, {Id: 2}}
+	tests := []struct {
+		name     string
+		chosen   []string
+		multiple bool
+		want     []int
+		wantErr  bool
+	}{
+		{"single choice", []string{"1"}, false, []int{1}, false},
+		{"nothing picked", nil, false, nil, true},
+		{"unknown option", []string{"3"}, false, nil, true},
+		{"too many for single choice", []string{"1", "2"}, false, nil, true},
+		{"multiple choice", []string{"1", "2"}, true, []int{1, 2}, false},
+		{"duplicate option", []string{"1", "1"}, true, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errors := pickPollOptions(options, tt.chosen, tt.multiple, ln)
+			if (len(errors) != 0) != tt.wantErr {
+				t.Errorf("pickPollOptions() errors = %v, wantErr %v", errors, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pickPollOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelPollVotes(t *testing.T) {
+	m := newTestModel(t)
+	poll := Node{DomainId: 1, Title: "Poll", Kind: kindPoll, Status: statusEnabled, Level: levelRoot, Options: []string{"A", "B"}}
+	listId, err := m.addList(&poll, titledItems(poll.Options))
+	if err != nil {
+		t.Fatalf("addList() error = %v", err)
+	}
+	options := m.mustGetChildNodes(1, listId, itemsPerPage, 0, "id")
+	if len(*options) != 2 {
+		t.Fatalf("addList() stored %d options, want 2", len(*options))
+	}
+	itemId := (*options)[0].Id
+	if err := m.addPollVotes(1, listId, []int{itemId}, "ip:x"); err != nil {
+		t.Fatalf("addPollVotes() error = %v", err)
+	}
+	otherId := (*options)[1].Id
+	if err := m.addPollVotes(1, listId, []int{otherId}, "ip:x"); err != errAlreadyVoted {
+		t.Errorf("addPollVotes() of another option error = %v, want %v", err, errAlreadyVoted)
+	}
+	// A failing vote is rolled back as a whole
+	if err := m.addPollVotes(1, listId, []int{otherId, otherId}, "ip:y"); err == nil {
+		t.Error("addPollVotes() expected an error for a repeated option")
+	}
+	if voted, _ := m.hasPollVote(listId, "ip:y"); voted {
+		t.Error("hasPollVote() of the failed vote = true, want false")
+	}
+	if other, _ := m.getNode(1, otherId); other.Up != 0 {
+		t.Errorf("failed vote counted %d up votes, want 0", other.Up)
+	}
+	if voted, _ := m.hasPollVote(listId, "ip:x"); !voted {
+		t.Error("hasPollVote() = false, want true")
+	}
+	if err := m.recountVotes(1); err != nil {
+		t.Fatal(err)
+	}
+	item, _ := m.getNode(1, itemId)
+	list, _ := m.getNode(1, listId)
+	if item.Up != 1 || list.Vote != 1 {
+		t.Errorf("counters = %d/%d, want 1/1", item.Up, list.Vote)
+	}
+}
+
+func TestVoterKey(t *testing.T) {
+	l := &ListBoard{config: &Config{}, m: newTestModel(t)}
+	vote := func(remoteAddr, password string) string {
+		r := httptest.NewRequest("POST", "/list/1/poll?password="+password, nil)
+		r.RemoteAddr = remoteAddr
+		return l.voterKey(r, 1)
+	}
+	if first, second := vote("1.1.1.1:1", "one"), vote("1.1.1.1:2", "two"); first != second {
+		t.Errorf("voterKey() = %q and %q, want a new password to keep the voter", first, second)
+	}
+	if first, second := vote("1.1.1.1:1", ""), vote("2.2.2.2:1", ""); first == second {
+		t.Errorf("voterKey() = %q for different addresses", first)
+	}
+	r := httptest.NewRequest("POST", "/api/poll/1", nil)
+	if got := (&ApiToken{AccountId: 7}).voterKey(r); got != "account:7" {
+		t.Errorf("voterKey() of an account token = %q, want account:7", got)
+	}
+	if got := (&ApiToken{Tripcode: "!trip"}).voterKey(r); got != voterKey(r, 0) {
+		t.Errorf("voterKey() of a tripcode token = %q, want the address", got)
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxPreviewsPerNode = 3
+	fetchTimeout       = 5 * time.Second
+	fetchMaxSize       = 2 << 20
+	// previewBudget limits the time spent fetching all previews of a node
+	previewBudget = 5 * time.Second
+	// retryFailedAfter is the time before a failed preview is fetched again
+	retryFailedAfter = time.Hour
+)
+
+// defaultPreviewHosts are the known hosts links are previewed for
+var defaultPreviewHosts = []string{"youtube.com", "www.youtube.com", "m.youtube.com", "youtu.be", "vimeo.com"}
+
+// defaultImageHosts serve the preview images of the known hosts
+var defaultImageHosts = []string{"i.ytimg.com", "img.youtube.com", "i.vimeocdn.com"}
+
+var (
+	errPreviewFailed  = errors.New("fetching the preview failed recently")
+	errPrivateAddress = errors.New("fetching from private addresses is not allowed")
+)
+
+var linkRe = regexp.MustCompile(`https?://[^\s<>()\[\]"']+`)
+
+type PreviewConfig struct {
+	Enabled bool     `json:"enabled"`
+	Hosts   []string `json:"hosts"`
+	// ImageHosts may serve the preview images besides the hosts
+	ImageHosts []string `json:"image_hosts"`
+}
+
+// LinkPreview holds the OpenGraph metadata of a link. The image is a local
+// copy so visitors don't load anything from third parties.
+type LinkPreview struct {
+	URL         string    `db:"url" json:"url"`
+	Title       string    `db:"title" json:"title"`
+	Description string    `db:"description" json:"description"`
+	Image       string    `db:"image" json:"image"`
+	VideoId     string    `db:"video_id" json:"video_id"`
+	Fetched     time.Time `db:"fetched" json:"-"`
+	// Failed is set when the page could not be fetched
+	Failed bool `db:"failed" json:"-"`
+}
+
+// Fetcher downloads the content of an URL
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) ([]byte, error)
+}
+
+type httpFetcher struct {
+	client *http.Client
+}
+
+// NewHttpFetcher returns a fetcher which connects only to public addresses,
+// also after redirects
+func NewHttpFetcher() Fetcher {
+	dialer := &net.Dialer{Timeout: fetchTimeout, Control: publicOnly}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: fetchTimeout,
+	}
+	return &httpFetcher{client: &http.Client{Timeout: fetchTimeout, Transport: transport}}
+}
+
+// publicOnly refuses connections to loopback, private and other non public
+// addresses
+func publicOnly(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+func (hf *httpFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := hf.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s returned %d", url, res.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, fetchMaxSize))
+}
+
+func (pc *PreviewConfig) knownHost(host string) bool {
+	hosts := pc.Hosts
+	if hosts == nil {
+		hosts = defaultPreviewHosts
+	}
+	return containsHost(hosts, host)
+}
+
+// imageURL tells if the preview image may be copied from the URL
+func (pc *PreviewConfig) imageURL(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	hosts := pc.ImageHosts
+	if hosts == nil {
+		hosts = defaultImageHosts
+	}
+	return pc.knownHost(u.Hostname()) || containsHost(hosts, u.Hostname())
+}
+
+func containsHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// previewLinks returns the links of the text pointing to known hosts
+func (pc *PreviewConfig) previewLinks(text string) []string {
+	var links []string
+	seen := make(map[string]bool)
+	for _, link := range linkRe.FindAllString(text, -1) {
+		u, err := url.Parse(link)
+		if err != nil || seen[link] || !pc.knownHost(u.Hostname()) {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+		if len(links) == maxPreviewsPerNode {
+			break
+		}
+	}
+	return links
+}
+
+// youtubeId returns the video id of YouTube links
+func youtubeId(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	switch strings.ToLower(u.Hostname()) {
+	case "youtu.be":
+		return strings.Trim(u.Path, "/")
+	case "youtube.com", "www.youtube.com", "m.youtube.com":
+		return u.Query().Get("v")
+	}
+	return ""
+}
+
+// parseOpenGraph reads the OpenGraph title, description and image of a page
+// falling back to its title tag
+func parseOpenGraph(page []byte) LinkPreview {
+	var lp LinkPreview
+	var title string
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if lp.Title == "" {
+				lp.Title = strings.TrimSpace(title)
+			}
+			return lp
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "title":
+				if z.Next() == html.TextToken {
+					title = string(z.Text())
+				}
+			case "meta":
+				var property, content string
+				for _, a := range t.Attr {
+					switch a.Key {
+					case "property", "name":
+						property = a.Val
+					case "content":
+						content = a.Val
+					}
+				}
+				switch property {
+				case "og:title":
+					lp.Title = content
+				case "og:description":
+					lp.Description = content
+				case "og:image":
+					lp.Image = content
+				}
+			}
+		case html.EndTagToken:
+			if z.Token().Data == "head" {
+				if lp.Title == "" {
+					lp.Title = strings.TrimSpace(title)
+				}
+				return lp
+			}
+		}
+	}
+}
+
+func (m *Model) getLinkPreview(url string) (*LinkPreview, error) {
+	var lp LinkPreview
+	err := m.db.Get(&lp, "SELECT * FROM link_preview WHERE url=$1", url)
+	return &lp, err
+}
+
+func (m *Model) saveLinkPreview(lp *LinkPreview) error {
+	_, err := m.db.NamedExec(`INSERT OR REPLACE INTO link_preview (
+			url,
+			title,
+			description,
+			image,
+			video_id,
+			fetched,
+			failed
+		) VALUES (
+			:url,
+			:title,
+			:description,
+			:image,
+			:video_id,
+			:fetched,
+			:failed
+		)`, lp)
+	return err
+}
+
+// linkPreview returns the cached preview of the link or fetches it. Failures
+// are cached too and retried after a while.
+func (l *ListBoard) linkPreview(ctx context.Context, pc *PreviewConfig, link string) (*LinkPreview, error) {
+	if lp, err := l.m.getLinkPreview(link); err == nil {
+		if !lp.Failed {
+			return lp, nil
+		}
+		if time.Since(lp.Fetched) < retryFailedAfter {
+			return nil, errPreviewFailed
+		}
+	}
+	page, err := l.fetcher.Fetch(ctx, link)
+	if err != nil {
+		// Running out of time is not the fault of the link
+		if ctx.Err() == nil {
+			failed := LinkPreview{URL: link, Fetched: time.Now(), Failed: true}
+			if err := l.m.saveLinkPreview(&failed); err != nil {
+				log.Printf("Error saving failed preview for %s: %s", link, err)
+			}
+		}
+		return nil, err
+	}
+	lp := parseOpenGraph(page)
+	image := lp.Image
+	lp.URL = link
+	lp.Image = ""
+	lp.VideoId = youtubeId(link)
+	lp.Fetched = time.Now()
+	if image != "" {
+		// Keep a local copy of the image
+		if imageURL, err := url.Parse(link); err == nil {
+			if imageURL, err = imageURL.Parse(image); err == nil && pc.imageURL(imageURL) {
+				if data, err := l.fetcher.Fetch(ctx, imageURL.String()); err == nil {
+					if result, err := storeImage(l.store, data, l.config.Uploads.ThumbnailSize); err == nil {
+						lp.Image = result.Thumbnail
+					}
+				}
+			}
+		}
+	}
+	return &lp, l.m.saveLinkPreview(&lp)
+}
+
+// linkPreviews returns the previews of the known links in the text encoded
+// for storing with the node. All fetches share previewBudget so posting
+// doesn't wait long for slow hosts.
+func (l *ListBoard) linkPreviews(sc *SiteConfig, text string) string {
+	if !sc.Previews.Enabled || l.fetcher == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), previewBudget)
+	defer cancel()
+	var previews []LinkPreview
+	for _, link := range sc.Previews.previewLinks(text) {
+		lp, err := l.linkPreview(ctx, &sc.Previews, link)
+		if err == errPreviewFailed {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error fetching preview for %s: %s", link, err)
+			continue
+		}
+		previews = append(previews, *lp)
+	}
+	if len(previews) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(previews)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// LinkPreviews decodes the previews stored with the node
+func (n Node) LinkPreviews() []LinkPreview {
+	var previews []LinkPreview
+	if n.Previews != "" {
+		if err := json.Unmarshal([]byte(n.Previews), &previews); err != nil {
+			log.Printf("Error decoding previews of node %d: %s", n.Id, err)
+		}
+	}
+	return previews
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+type stubFetcher map[string][]byte
+
+func (sf stubFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	data, ok := sf[url]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+const testPage = `<html><head>
+<title>Fallback</title>
+<meta property="og:title" content="Video title" />
+<meta property="og:description" content="About the video" />
+<meta property="og:image" content="/thumb.png" />
+</head><body></body></html>`
+
+func TestParseOpenGraph(t *testing.T) {
+	got := parseOpenGraph([]byte(testPage))
+	want := LinkPreview{Title: "Video title", Description: "About the video", Image: "/thumb.png"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseOpenGraph() = %v, want %v", got, want)
+	}
+	if got := parseOpenGraph([]byte("<title>Only title</title>")); got.Title != "Only title" {
+		t.Errorf("parseOpenGraph() title = %v, want Only title", got.Title)
+	}
+}
+
+func TestPreviewLinks(t *testing.T) {
+	pc := &PreviewConfig{Enabled: true}
+	text := "see https://www.youtube.com/watch?v=abc and https://example.com/x and (https://youtu.be/def)"
+	want := []string{"https://www.youtube.com/watch?v=abc", "https://youtu.be/def"}
+	if got := pc.previewLinks(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("previewLinks() = %v, want %v", got, want)
+	}
+	if got := youtubeId(want[0]); got != "abc" {
+		t.Errorf("youtubeId() = %v, want abc", got)
+	}
+	if got := youtubeId(want[1]); got != "def" {
+		t.Errorf("youtubeId() = %v, want def", got)
+	}
+}
+
+func TestLinkPreviews(t *testing.T) {
+	store := &memoryStore{files: make(map[string][]byte)}
+	fetcher := stubFetcher{
+		"https://youtu.be/abc":       []byte(testPage),
+		"https://youtu.be/thumb.png": testPng(t),
+	}
+	l := &ListBoard{
+		config:  &Config{Uploads: UploadConfig{ThumbnailSize: 320}},
+		m:       newTestModel(t),
+		store:   store,
+		fetcher: fetcher,
+	}
+	sc := &SiteConfig{Previews: PreviewConfig{Enabled: true}}
+	node := Node{Previews: l.linkPreviews(sc, "watch https://youtu.be/abc")}
+	previews := node.LinkPreviews()
+	if len(previews) != 1 {
+		t.Fatalf("LinkPreviews() = %v, want one preview", previews)
+	}
+	if previews[0].Title != "Video title" || previews[0].VideoId != "abc" {
+		t.Errorf("LinkPreviews() = %v, unexpected preview", previews[0])
+	}
+	if previews[0].Image == "" || store.puts != 2 {
+		t.Errorf("Expected a local copy of the image, got %v", previews[0].Image)
+	}
+	// The second call is served from the cache
+	delete(fetcher, "https://youtu.be/abc")
+	if got := l.linkPreviews(sc, "https://youtu.be/abc"); got != node.Previews {
+		t.Errorf("linkPreviews() = %v, want cached %v", got, node.Previews)
+	}
+}
+
+// countingFetcher counts the fetches of each URL
+type countingFetcher struct {
+	stubFetcher
+	fetches map[string]int
+}
+
+func (cf *countingFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	cf.fetches[url]++
+	return cf.stubFetcher.Fetch(ctx, url)
+}
+
+func TestLinkPreviewHosts(t *testing.T) {
+	store := &memoryStore{files: make(map[string][]byte)}
+	fetcher := &countingFetcher{
+		stubFetcher: stubFetcher{
+			"https://youtu.be/abc":             []byte(`<meta property="og:image" content="http://127.0.0.1/admin" />`),
+			"https://youtu.be/def":             []byte(`<meta property="og:image" content="https://i.ytimg.com/vi/def/0.jpg" />`),
+			"https://i.ytimg.com/vi/def/0.jpg": testPng(t),
+			"http://127.0.0.1/admin":           testPng(t),
+		},
+		fetches: make(map[string]int),
+	}
+	l := &ListBoard{
+		config:  &Config{Uploads: UploadConfig{ThumbnailSize: 320}},
+		m:       newTestModel(t),
+		store:   store,
+		fetcher: fetcher,
+	}
+	sc := &SiteConfig{Previews: PreviewConfig{Enabled: true}}
+
+	t.Run("images only from the image hosts", func(t *testing.T) {
+		node := Node{Previews: l.linkPreviews(sc, "https://youtu.be/abc https://youtu.be/def")}
+		previews := node.LinkPreviews()
+		if len(previews) != 2 || previews[0].Image != "" || previews[1].Image == "" {
+			t.Errorf("LinkPreviews() = %v, want only the second image", previews)
+		}
+		if fetcher.fetches["http://127.0.0.1/admin"] != 0 {
+			t.Error("the image of an unknown host was fetched")
+		}
+	})
+
+	t.Run("failures are cached", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if got := l.linkPreviews(sc, "https://youtu.be/missing"); got != "" {
+				t.Errorf("linkPreviews() = %v, want none", got)
+			}
+		}
+		if got := fetcher.fetches["https://youtu.be/missing"]; got != 1 {
+			t.Errorf("the failed link was fetched %d times, want 1", got)
+		}
+	})
+}
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1::1", true},
+		{"127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+	}
+	for _, tt := range tests {
+		if got := publicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("publicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
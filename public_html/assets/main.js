@@ -1,65 +1,45 @@
-// https://hacks.mozilla.org/2011/03/the-shortest-image-uploader-ever/
+// Uploads the image and appends the returned markdown to the post
 function upload(file) {
-	/* Is the file an image? */
 	if (!file || !file.type.match(/image.*/)) return;
 
-	/* Lets build a FormData object*/
-	var fd = new FormData(); // I wrote about it: https://hacks.mozilla.org/2011/01/how-to-develop-a-html5-image-uploader/
-	fd.append("image", file); // Append the file
-	var xhr = new XMLHttpRequest(); // Create the XHR (Cross-Domain XHR FTW!!!) Thank you sooooo much imgur.com
-	xhr.open("POST", "https://api.imgur.com/3/upload.json"); // Boooom!
-	xhr.setRequestHeader('Authorization', 'Client-ID c2e15b62bf762a8');
+	var textarea = document.getElementById('textarea'),
+		csrf = textarea.form.elements['csrf'],
+		fd = new FormData();
+	fd.append('image', file);
+	fd.append('csrf', csrf ? csrf.value : '');
+	var xhr = new XMLHttpRequest();
+	xhr.open('POST', '/upload');
 	xhr.onload = function() {
-			// Big win!
-			var data = JSON.parse(xhr.responseText),
-				textarea = document.getElementById('textarea'),
-				texta = textarea.value.split("\n");
-			if (data.data && data.data.link) {
-				link = "![Image](" + data.data.link + ")";
-				texta.push(link);
-				textarea.value = texta.join("\n");
-			}
-			//document.querySelector("#link").href = JSON.parse(xhr.responseText).upload.links.imgur_page;
+		var data = JSON.parse(xhr.responseText),
+			texta = textarea.value.split("\n");
+		if (data.markdown) {
+			texta.push(data.markdown);
+			textarea.value = texta.join("\n");
+		} else if (data.errors) {
+			alert(data.errors.join("\n"));
 		}
-		/* And now, we send the formdata */
+	};
 	xhr.send(fd);
 }
 
 function clickHandler(videoId) {
-	return function() {
-
-		// Create an iFrame with autoplay set to true
+	return function(e) {
+		e.preventDefault();
+		// Replace the preview with the player only when asked to
 		var iframe = document.createElement("iframe");
 		iframe.setAttribute("src",
-			"https://www.youtube.com/embed/" + videoId + "?autoplay=1&autohide=1&border=0&wmode=opaque&enablejsapi=1");
-
-		// The height and width of the iFrame should be the same as parent
-		iframe.className = 'video_frame'
-		// Replace the YouTube thumbnail with YouTube HTML5 Player
+			"https://www.youtube-nocookie.com/embed/" + videoId + "?autoplay=1");
+		iframe.setAttribute("allowfullscreen", "allowfullscreen");
+		iframe.className = 'video_frame';
 		this.parentNode.replaceChild(iframe, this);
 	}
 };
 
+// Turns the server side YouTube previews into click to play embeds
 function youtube() {
-	var re = /youtube\.com\/watch\?.*v=(.+?)(&|$)/
-	var links = document.getElementsByTagName('a');
-	var len = links.length;
-	for (var i = 0; i < len; i++) {
-		var href = links[i].href;
-		var matches = href.match(re);
-		if (matches) {
-			var videoId = matches[1];
-			var div = document.createElement('div');
-			div.className = 'youtube';
-
-			var image = document.createElement('img');
-			image.className = 'thumb'
-			image.setAttribute('src', 'http://i.ytimg.com/vi/'+videoId+'/hqdefault.jpg');
-			div.appendChild(image);
-
-			image.onclick = clickHandler(videoId)
-			links[i].parentElement.insertBefore(div, links[i]);
-		}
+	var links = document.querySelectorAll('.preview a[data-video]');
+	for (var i = 0; i < links.length; i++) {
+		links[i].onclick = clickHandler(links[i].getAttribute('data-video'));
 	}
 }
 youtube();
@@ -85,3 +65,49 @@ function tgl(elem, max) {
 		rsz(elem, max);
 	}
 }
+
+// Shows a preview of the referenced post when it is on the same page
+function refPreviews() {
+	var preview = document.createElement('div');
+	preview.className = 'ref_preview';
+	document.body.appendChild(preview);
+	var links = document.querySelectorAll('.txt a, .meta a');
+	for (var i = 0; i < links.length; i++) {
+		var link = links[i];
+		if (link.pathname === undefined || link.hash.indexOf('#I') !== 0) continue;
+		link.onmouseover = function(e) {
+			var target = document.getElementById(this.hash.substring(1));
+			if (!target || this.pathname.split('/').slice(1, 3).join('/') !== location.pathname.split('/').slice(1, 3).join('/')) return;
+			var article = target.parentNode.cloneNode(true);
+			preview.innerHTML = '';
+			preview.appendChild(article);
+			preview.style.left = (e.pageX + 10) + 'px';
+			preview.style.top = (e.pageY + 10) + 'px';
+			preview.style.display = 'block';
+		};
+		link.onmouseout = function() {
+			preview.style.display = 'none';
+		};
+	}
+}
+refPreviews();
+
+// Shows the rendered post and its validation errors without saving it
+function preview(form) {
+	var xhr = new XMLHttpRequest();
+	xhr.open('POST', '/preview');
+	xhr.onload = function() {
+		var json = /json/.test(xhr.getResponseHeader('Content-Type') || ''),
+			data = json ? JSON.parse(xhr.responseText) : {errors: [xhr.responseText]},
+			errors = document.getElementById('preview_errors');
+		errors.innerHTML = '';
+		(data.errors || []).forEach(function(error) {
+			var p = document.createElement('p');
+			p.className = 'error';
+			p.textContent = error;
+			errors.appendChild(p);
+		});
+		document.getElementById('preview').innerHTML = data.rendered || '';
+	};
+	xhr.send(new FormData(form));
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RankedResult is the outcome of a ranked choice list for a single item
+type RankedResult struct {
+	Item  Node `json:"item"`
+	Place int  `json:"place"`
+	Borda int  `json:"borda"`
+	// Rounds holds the first choices of the item in every instant-runoff
+	// round or -1 once it's eliminated
+	Rounds []int `json:"rounds"`
+}
+
+// instantRunoff eliminates the candidate with the fewest first choices
+// until one remains and returns the first choices per round and the
+// candidates from the winner to the first eliminated. Ties are eliminated
+// newest first.
+func instantRunoff(candidates []int, ballots [][]int) ([]map[int]int, []int) {
+	remaining := make(map[int]bool)
+	for _, id := range candidates {
+		remaining[id] = true
+	}
+	var rounds []map[int]int
+	var eliminated []int
+	for len(remaining) > 0 {
+		counts := make(map[int]int)
+		for id := range remaining {
+			counts[id] = 0
+		}
+		for _, ballot := range ballots {
+			for _, id := range ballot {
+				if remaining[id] {
+					counts[id]++
+					break
+				}
+			}
+		}
+		rounds = append(rounds, counts)
+		loser := 0
+		for id, count := range counts {
+			if loser == 0 || count < counts[loser] || (count == counts[loser] && id > loser) {
+				loser = id
+			}
+		}
+		delete(remaining, loser)
+		eliminated = append(eliminated, loser)
+	}
+	order := make([]int, len(eliminated))
+	for i, id := range eliminated {
+		order[len(eliminated)-1-i] = id
+	}
+	return rounds, order
+}
+
+// bordaCount gives every candidate a point for each candidate ranked below
+// it on a ballot. Unranked candidates get nothing.
+func bordaCount(candidates []int, ballots [][]int) map[int]int {
+	points := make(map[int]int)
+	for _, id := range candidates {
+		points[id] = 0
+	}
+	for _, ballot := range ballots {
+		for i, id := range ballot {
+			if _, ok := points[id]; ok {
+				points[id] += len(candidates) - 1 - i
+			}
+		}
+	}
+	return points
+}
+
+// rankedResults combines the instant-runoff and Borda count outcomes ordered
+// by the instant-runoff place
+func rankedResults(items NodeList, ballots [][]int) []RankedResult {
+	candidates := items.Ids()
+	rounds, order := instantRunoff(candidates, ballots)
+	points := bordaCount(candidates, ballots)
+	place := make(map[int]int)
+	for i, id := range order {
+		place[id] = i + 1
+	}
+	results := make([]RankedResult, len(items))
+	for i, item := range items {
+		results[i] = RankedResult{Item: item, Place: place[item.Id], Borda: points[item.Id]}
+		for _, counts := range rounds {
+			count, ok := counts[item.Id]
+			if !ok {
+				count = -1
+			}
+			results[i].Rounds = append(results[i].Rounds, count)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Place < results[j].Place
+	})
+	return results
+}
+
+// parseBallot orders the items by the ranks given to them. Every item must
+// get a distinct rank between 1 and the number of items.
+func parseBallot(items NodeList, ranks map[int]string, ln *Language) ([]int, ValidationErrors) {
+	ballot := make([]int, len(items))
+	for _, item := range items {
+		rank, err := strconv.Atoi(ranks[item.Id])
+		if err != nil {
+			return nil, ValidationErrors{ln.Lang("Please, rank all items")}
+		}
+		if rank < 1 || rank > len(items) || ballot[rank-1] != 0 {
+			return nil, ValidationErrors{ln.Lang("Every item needs a different rank")}
+		}
+		ballot[rank-1] = item.Id
+	}
+	return ballot, nil
+}
+
+// getRankedItems returns the items that can be ranked
+func (m *Model) getRankedItems(domainId, listId int) (*NodeList, error) {
+	return m.getChildNodes(domainId, listId, itemsPerPage, 0, "id")
+}
+
+// getBallot returns the item ids in the order ranked by the voter
+func (m *Model) getBallot(listId int, voter string) ([]int, error) {
+	var ballot []int
+	err := m.db.Select(&ballot, "SELECT item_id FROM ranked_ballot WHERE list_id = $1 AND voter = $2 ORDER BY position", listId, voter)
+	return ballot, err
+}
+
+func (m *Model) getBallots(listId int) ([][]int, error) {
+	var rows []struct {
+		Voter  string `db:"voter"`
+		ItemId int    `db:"item_id"`
+	}
+	err := m.db.Select(&rows, "SELECT voter, item_id FROM ranked_ballot WHERE list_id = $1 ORDER BY voter, position", listId)
+	if err != nil {
+		return nil, err
+	}
+	var ballots [][]int
+	for i, row := range rows {
+		if i == 0 || rows[i-1].Voter != row.Voter {
+			ballots = append(ballots, nil)
+		}
+		ballots[len(ballots)-1] = append(ballots[len(ballots)-1], row.ItemId)
+	}
+	return ballots, nil
+}
+
+// setBallot replaces the ranking of the voter. The list counts the ballots
+// as its votes.
+func (m *Model) setBallot(domainId, listId int, voter string, ballot []int) error {
+	previous, err := m.getBallot(listId, voter)
+	if err != nil {
+		return err
+	}
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM ranked_ballot WHERE list_id = $1 AND voter = $2", listId, voter); err != nil {
+		tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	for position, itemId := range ballot {
+		_, err := tx.Exec("INSERT INTO ranked_ballot (list_id, voter, item_id, position, created) VALUES ($1, $2, $3, $4, $5)",
+			listId, voter, itemId, position, now)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if len(previous) == 0 {
+		return m.bumpVote(domainId, listId, 1)
+	}
+	return nil
+}
+
+// getRankedResults tallies the ballots of the list
+func (l *ListBoard) getRankedResults(domainId, listId int) ([]RankedResult, int, error) {
+	items, err := l.m.getRankedItems(domainId, listId)
+	if err != nil {
+		return nil, 0, err
+	}
+	ballots, err := l.m.getBallots(listId)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rankedResults(*items, ballots), len(ballots), nil
+}
+
+// castBallot validates and stores the ranking posted in the list form
+func (l *ListBoard) castBallot(r *http.Request, list *Node, ln *Language) ValidationErrors {
+	if errors := closedErrors(list, ln); len(errors) != 0 {
+		return errors
+	}
+	if errors := votingErrors(list, l.credentials(r, list.DomainId).identified(), ln); len(errors) != 0 {
+		return errors
+	}
+	items, err := l.m.getRankedItems(list.DomainId, list.Id)
+	if err != nil {
+		return ValidationErrors{err.Error()}
+	}
+	ranks := make(map[int]string)
+	for _, item := range *items {
+		ranks[item.Id] = r.FormValue("rank_" + strconv.Itoa(item.Id))
+	}
+	ballot, errors := parseBallot(*items, ranks, ln)
+	if len(errors) != 0 {
+		return errors
+	}
+	if err := l.m.setBallot(list.DomainId, list.Id, l.voterKey(r, list.DomainId), ballot); err != nil {
+		return ValidationErrors{err.Error()}
+	}
+	return nil
+}
+
+// setRankedSession adds the ballot form and the results to the list page
+func (l *ListBoard) setRankedSession(s *Session, r *http.Request, list *Node) error {
+	results, ballots, err := l.getRankedResults(list.DomainId, list.Id)
+	if err != nil {
+		return err
+	}
+	ballot, err := l.m.getBallot(list.Id, l.voterKey(r, list.DomainId))
+	if err != nil {
+		return err
+	}
+	ranks := make(map[int]int)
+	for i, id := range ballot {
+		ranks[id] = i + 1
+	}
+	var rounds []int
+	if len(results) > 0 {
+		for i := range results[0].Rounds {
+			rounds = append(rounds, i+1)
+		}
+	}
+	s.Set("Ranked", true)
+	s.Set("RankedResults", results)
+	s.Set("RankedRounds", rounds)
+	s.Set("Ballots", ballots)
+	s.Set("BallotRanks", ranks)
+	return nil
+}
+
+// csvCell keeps spreadsheets from running user text as a formula by
+// prefixing it with a quote
+func csvCell(s string) string {
+	if s != "" && strings.ContainsAny(s[:1], "=+-@\t\r") {
+		return "'" + s
+	}
+	return s
+}
+
+// rankedExportHandler exports the results of a ranked choice list as CSV
+func (l *ListBoard) rankedExportHandler(w http.ResponseWriter, r *http.Request) error {
+	listId, err := strconv.Atoi(mux.Vars(r)["listId"])
+	if err != nil {
+		return err
+	}
+	sc := l.config.getSiteConfig(l.getToken(r))
+	list, err := l.m.getNode(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	if !list.IsRanked() {
+		return HTTPError{Message: "Not a ranked choice list", Code: http.StatusNotFound}
+	}
+	results, _, err := l.getRankedResults(sc.DomainId, listId)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="results-`+strconv.Itoa(listId)+`.csv"`)
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "title", "place", "borda", "first_choices"}); err != nil {
+		return err
+	}
+	for _, result := range results {
+		first := 0
+		if len(result.Rounds) > 0 {
+			first = result.Rounds[0]
+		}
+		err := cw.Write([]string{
+			strconv.Itoa(result.Item.Id),
+			csvCell(result.Item.Title),
+			strconv.Itoa(result.Place),
+			strconv.Itoa(result.Borda),
+			strconv.Itoa(first),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInstantRunoff(t *testing.T) {
+	// 1 leads the first choices but 3 wins once 2 is eliminated
+	ballots := [][]int{
+		{1, 2, 3}, {1, 2, 3}, {1, 3, 2},
+		{2, 3, 1},
+		{3, 2, 1}, {3, 2, 1}, {3, 1, 2},
+	}
+	rounds, order := instantRunoff([]int{1, 2, 3}, ballots)
+	if !reflect.DeepEqual(order, []int{3, 1, 2}) {
+		t.Errorf("instantRunoff() order = %v, want [3 1 2]", order)
+	}
+	if !reflect.DeepEqual(rounds[1], map[int]int{1: 3, 3: 4}) {
+		t.Errorf("instantRunoff() second round = %v, want map[1:3 3:4]", rounds[1])
+	}
+}
+
+func TestInstantRunoffTies(t *testing.T) {
+	// the newest candidate goes first on a tie and empty ballots count nothing
+	_, order := instantRunoff([]int{1, 2}, [][]int{{1}, {2}, {}})
+	if !reflect.DeepEqual(order, []int{1, 2}) {
+		t.Errorf("instantRunoff() order = %v, want [1 2]", order)
+	}
+}
+
+func TestBordaCount(t *testing.T) {
+	got := bordaCount([]int{1, 2, 3}, [][]int{{1, 2, 3}, {2, 1}, {3}})
+	want := map[int]int{1: 3, 2: 3, 3: 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bordaCount() = %v, want %v", got, want)
+	}
+}
+
+func TestParseBallot(t *testing.T) {
+	ln := &Language{}
+	items := NodeList{{Id: 5}, {Id: 7}}
+	tests := []struct {
+		name    string
+		ranks   map[int]string
+		want    []int
+		wantErr bool
+	}{
+		{"orders by rank", map[int]string{5: "2", 7: "1"}, []int{7, 5}, false},
+		{"missing rank", map[int]string{5: "1"}, nil, true},
+		{"same rank", map[int]string{5: "1", 7: "1"}, nil, true},
+		{"out of range", map[int]string{5: "1", 7: "3"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errors := parseBallot(items, tt.ranks, ln)
+			if (len(errors) != 0) != tt.wantErr {
+				t.Errorf("parseBallot() errors = %v, wantErr %v", errors, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseBallot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelBallots(t *testing.T) {
+	m := newTestModel(t)
+	listId, _ := m.addNode(&Node{DomainId: 1, Title: "List", Kind: kindRanked, Status: statusEnabled, Level: levelRoot})
+	if err := m.setBallot(1, listId, "ip:a", []int{2, 3}); err != nil {
+		t.Fatalf("setBallot() error = %v", err)
+	}
+	if err := m.setBallot(1, listId, "ip:a", []int{3, 2}); err != nil {
+		t.Fatalf("setBallot() error = %v", err)
+	}
+	m.setBallot(1, listId, "ip:b", []int{2})
+	ballots, err := m.getBallots(listId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ballots, [][]int{{3, 2}, {2}}) {
+		t.Errorf("getBallots() = %v, want [[3 2] [2]]", ballots)
+	}
+	list, _ := m.getNode(1, listId)
+	if list.Vote != 2 {
+		t.Errorf("list vote = %d, want 2", list.Vote)
+	}
+}
+
+func TestCsvCell(t *testing.T) {
+	tests := map[string]string{
+		"Pizza":             "Pizza",
+		"":                  "",
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1":                "'+1",
+		"-1":                "'-1",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"a=b":               "a=b",
+	}
+	for in, want := range tests {
+		if got := csvCell(in); got != want {
+			t.Errorf("csvCell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	rankScore         = "score"
+	rankWilson        = "wilson"
+	rankHot           = "hot"
+	rankNewest        = "newest"
+	rankControversial = "controversial"
+
+	// hotEpoch is the reference time of the hot ranking
+	hotEpoch = 1134028003
+)
+
+type selectOption struct {
+	Value string
+	Label string
+}
+
+// rankingOptions are offered in the list form
+var rankingOptions = []selectOption{
+	{rankScore, "Top score"},
+	{rankWilson, "Best rated"},
+	{rankHot, "Hot"},
+	{rankNewest, "Newest"},
+	{rankControversial, "Controversial"},
+}
+
+// rankingOrders break ties by the id, which follows the creation order
+var rankingOrders = map[string]string{
+	rankScore:         "vote DESC, id",
+	rankWilson:        "wilson DESC, id",
+	rankHot:           "hot DESC, id",
+	rankNewest:        "id DESC",
+	rankControversial: "controversy DESC, id",
+}
+
+// validRanking returns the ranking if it's known or an empty string
+func validRanking(ranking string) string {
+	if _, ok := rankingOrders[ranking]; ok {
+		return ranking
+	}
+	return ""
+}
+
+// rankingOrder returns the order of the first known ranking defaulting to
+// the raw score
+func rankingOrder(rankings ...string) string {
+	for _, ranking := range rankings {
+		if order, ok := rankingOrders[ranking]; ok {
+			return order
+		}
+	}
+	return rankingOrders[rankScore]
+}
+
+// wilsonScore is the lower bound of the Wilson score confidence interval
+// for the share of up votes
+func wilsonScore(up, down int) float64 {
+	n := float64(up + down)
+	if n == 0 {
+		return 0
+	}
+	const z = 1.96
+	p := float64(up) / n
+	return (p + z*z/(2*n) - z*math.Sqrt((p*(1-p)+z*z/(4*n))/n)) / (1 + z*z/n)
+}
+
+// hotScore ranks newer items higher, every 12.5 hours are worth as much as
+// ten times the score
+func hotScore(up, down int, created time.Time) float64 {
+	s := float64(up - down)
+	order := math.Log10(math.Max(math.Abs(s), 1))
+	sign := 0.0
+	if s > 0 {
+		sign = 1
+	} else if s < 0 {
+		sign = -1
+	}
+	seconds := float64(created.Unix() - hotEpoch)
+	return sign*order + seconds/45000
+}
+
+// controversyScore is high for items with many and evenly split votes
+func controversyScore(up, down int) float64 {
+	if up <= 0 || down <= 0 {
+		return 0
+	}
+	balance := float64(down) / float64(up)
+	if up < down {
+		balance = float64(up) / float64(down)
+	}
+	return math.Pow(float64(up+down), balance)
+}
+
+// countVote updates the counters of the item. sign is 1 when the vote is
+// added and -1 when it's removed.
+func (m *Model) countVote(domainId, itemId, vote, sign int) error {
+	return countVote(m.db, domainId, itemId, vote, sign)
+}
+
+// countVote updates the counters of the item in the database or in a
+// transaction
+func countVote(e sqlx.Ext, domainId, itemId, vote, sign int) error {
+	up, down, comments := 0, 0, 0
+	if vote > 0 {
+		up = sign
+	} else if vote < 0 {
+		down = sign
+	} else {
+		comments = sign
+	}
+	_, err := sqlx.NamedExec(e, `UPDATE node SET
+			vote = vote + :vote,
+			up = up + :up,
+			down = down + :down,
+			comments = comments + :comments,
+			updated = :updated
+			WHERE domain_id = :domain_id AND id = :id`,
+		map[string]interface{}{
+			"vote":      vote * sign,
+			"up":        up,
+			"down":      down,
+			"comments":  comments,
+			"updated":   time.Now(),
+			"domain_id": domainId,
+			"id":        itemId,
+		})
+	if err != nil {
+		return err
+	}
+	return updateRanks(e, domainId, itemId)
+}
+
+// recountVotes recalculates the vote counters of the items and the vote
+// totals of the lists from the stored vote nodes
+func (m *Model) recountVotes(domainId int) error {
+	queries := []string{
+		`UPDATE node SET
+			up = (SELECT COUNT(*) FROM node v WHERE v.parent_id = node.id AND v.level = $2 AND v.status = 1 AND v.vote > 0)
+				+ (SELECT COUNT(*) FROM poll_vote p WHERE p.item_id = node.id),
+			down = (SELECT COUNT(*) FROM node v WHERE v.parent_id = node.id AND v.level = $2 AND v.status = 1 AND v.vote < 0),
+			comments = (SELECT COUNT(*) FROM node v WHERE v.parent_id = node.id AND v.level = $2 AND v.status = 1 AND v.vote = 0)
+			WHERE domain_id = $1 AND level = $3`,
+		`UPDATE node SET vote = up - down WHERE domain_id = $1 AND level = $3`,
+		`UPDATE node SET
+			vote = (SELECT COUNT(*) FROM node v JOIN node i ON v.parent_id = i.id WHERE i.parent_id = node.id AND v.level = $2 AND v.status = 1)
+				+ (SELECT COUNT(*) FROM poll_vote p WHERE p.list_id = node.id)
+				+ (SELECT COUNT(DISTINCT voter) FROM ranked_ballot b WHERE b.list_id = node.id)
+			WHERE domain_id = $1 AND level = $4`,
+	}
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, domainId, levelVote, levelList, levelRoot); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// updateRanks recalculates the stored ranking scores of the node
+func (m *Model) updateRanks(domainId, id int) error {
+	return updateRanks(m.db, domainId, id)
+}
+
+func updateRanks(e sqlx.Ext, domainId, id int) error {
+	var node Node
+	if err := sqlx.Get(e, &node, "SELECT * FROM node WHERE id=$1 AND domain_id=$2", id, domainId); err != nil {
+		return err
+	}
+	_, err := sqlx.NamedExec(e, `UPDATE node SET
+			wilson = :wilson,
+			hot = :hot,
+			controversy = :controversy
+			WHERE domain_id = :domain_id AND id = :id`,
+		map[string]interface{}{
+			"wilson":      wilsonScore(node.Up, node.Down),
+			"hot":         hotScore(node.Up, node.Down, node.Created),
+			"controversy": controversyScore(node.Up, node.Down),
+			"domain_id":   domainId,
+			"id":          id,
+		})
+	return err
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRankingOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		rankings []string
+		want     string
+	}{
+		{"list ranking wins", []string{rankNewest, rankHot}, "id DESC"},
+		{"falls back to the site", []string{"", rankHot}, "hot DESC, id"},
+		{"defaults to the score", []string{"bogus", ""}, "vote DESC, id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rankingOrder(tt.rankings...); got != tt.want {
+				t.Errorf("rankingOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWilsonScore(t *testing.T) {
+	if got := wilsonScore(0, 0); got != 0 {
+		t.Errorf("wilsonScore(0, 0) = %v, want 0", got)
+	}
+	// A single up vote is less certain than a hundred mostly positive ones
+	if wilsonScore(1, 0) >= wilsonScore(90, 10) {
+		t.Errorf("wilsonScore(1, 0) = %v, expected below wilsonScore(90, 10) = %v", wilsonScore(1, 0), wilsonScore(90, 10))
+	}
+	if got := wilsonScore(10, 0); got <= 0 || got >= 1 {
+		t.Errorf("wilsonScore(10, 0) = %v, expected between 0 and 1", got)
+	}
+}
+
+func TestHotScore(t *testing.T) {
+	now := time.Now()
+	if hotScore(10, 0, now) <= hotScore(1, 0, now) {
+		t.Error("hotScore() expected more votes to rank higher")
+	}
+	if hotScore(1, 0, now) <= hotScore(10, 0, now.Add(-48*time.Hour)) {
+		t.Error("hotScore() expected newer items to rank higher")
+	}
+}
+
+func TestControversyScore(t *testing.T) {
+	if got := controversyScore(10, 0); got != 0 {
+		t.Errorf("controversyScore(10, 0) = %v, want 0", got)
+	}
+	if controversyScore(10, 10) <= controversyScore(18, 2) {
+		t.Error("controversyScore() expected evenly split votes to rank higher")
+	}
+}
+
+func TestModelCountVote(t *testing.T) {
+	m := newTestModel(t)
+	listId, _ := m.addNode(&Node{DomainId: 1, Title: "List", Status: statusEnabled, Level: levelRoot})
+	itemId, _ := m.addNode(&Node{DomainId: 1, ParentId: listId, Title: "Item", Status: statusEnabled, Level: levelList})
+	for _, vote := range []int{1, 1, -1, 0} {
+		if err := m.Vote(1, vote, 0, itemId, listId); err != nil {
+			t.Fatalf("Vote() error = %v", err)
+		}
+	}
+	if err := m.Unvote(1, 1, itemId, listId); err != nil {
+		t.Fatalf("Unvote() error = %v", err)
+	}
+	item, err := m.getNode(1, itemId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Vote != 0 || item.Up != 1 || item.Down != 1 || item.Comments != 1 {
+		t.Errorf("counters = %d/%d/%d/%d, want 0/1/1/1", item.Vote, item.Up, item.Down, item.Comments)
+	}
+	if item.Controversy != controversyScore(1, 1) || item.Wilson != wilsonScore(1, 1) {
+		t.Errorf("ranks = %v/%v, expected them recalculated", item.Controversy, item.Wilson)
+	}
+}
+
+func TestModelRecountVotes(t *testing.T) {
+	m := newTestModel(t)
+	listId, _ := m.addNode(&Node{DomainId: 1, Title: "List", Status: statusEnabled, Level: levelRoot})
+	itemId, _ := m.addNode(&Node{DomainId: 1, ParentId: listId, Title: "Item", Status: statusEnabled, Level: levelList})
+	for _, vote := range []int{1, 1, -1, 0} {
+		m.addNode(&Node{DomainId: 1, ParentId: itemId, Title: "Vote", Vote: vote, Status: statusEnabled, Level: levelVote})
+	}
+	m.addNode(&Node{DomainId: 1, ParentId: itemId, Title: "Vote", Vote: 1, Status: statusDeleted, Level: levelVote})
+	if err := m.recountVotes(1); err != nil {
+		t.Fatalf("recountVotes() error = %v", err)
+	}
+	item, _ := m.getNode(1, itemId)
+	if item.Vote != 1 || item.Up != 2 || item.Down != 1 || item.Comments != 1 {
+		t.Errorf("counters = %d/%d/%d/%d, want 1/2/1/1", item.Vote, item.Up, item.Down, item.Comments)
+	}
+	list, _ := m.getNode(1, listId)
+	if list.Vote != 4 {
+		t.Errorf("list vote = %d, want 4", list.Vote)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// refRe matches ">>123" and "#123" references to other nodes
+var refRe = regexp.MustCompile(`(^|[\s(])(>>|#)(\d+)\b`)
+
+// Backlinks maps node ids to the nodes referencing them
+type Backlinks map[int]NodeList
+
+type backlink struct {
+	RefId int `db:"ref_id"`
+	Node
+}
+
+func (nl *NodeList) Ids() []int {
+	ids := make([]int, len(*nl))
+	for i, node := range *nl {
+		ids[i] = node.Id
+	}
+	return ids
+}
+
+// findReferences returns the ids referenced in the text outside of code
+func findReferences(text string) []int {
+	var ids []int
+	seen := make(map[int]bool)
+	eachRefSegment(text, func(segment string) string {
+		for _, match := range refRe.FindAllStringSubmatch(segment, -1) {
+			id, err := strconv.Atoi(match[3])
+			if err == nil && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return segment
+	})
+	return ids
+}
+
+// linkReferences replaces the references to known nodes with markdown links
+func linkReferences(text string, nodes map[int]Node) string {
+	return eachRefSegment(text, func(segment string) string {
+		return refRe.ReplaceAllStringFunc(segment, func(match string) string {
+			parts := refRe.FindStringSubmatch(match)
+			// Escape the reference so the typographer keeps the >> as is
+			ref := strings.ReplaceAll(parts[2], ">", `\>`) + parts[3]
+			id, _ := strconv.Atoi(parts[3])
+			node, ok := nodes[id]
+			if !ok {
+				return parts[1] + ref
+			}
+			title := strings.NewReplacer(`"`, "", `\`, "").Replace(node.Title)
+			return parts[1] + "[" + ref + "](" + getUrl("", node) + ` "` + title + `")`
+		})
+	})
+}
+
+// eachRefSegment calls fn for the parts of the markdown text which are not
+// fenced, indented or inline code and joins the results
+func eachRefSegment(text string, fn func(string) string) string {
+	lines := strings.Split(text, "\n")
+	fenced := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		spans := strings.Split(line, "`")
+		for j := 0; j < len(spans); j += 2 {
+			spans[j] = fn(spans[j])
+		}
+		lines[i] = strings.Join(spans, "`")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *Model) getNodesById(domainId int, ids []int) (map[int]Node, error) {
+	nodes := make(map[int]Node)
+	if len(ids) == 0 {
+		return nodes, nil
+	}
+	query, args, err := sqlx.In("SELECT * FROM node WHERE domain_id = ? AND status = 1 AND id IN (?)", domainId, ids)
+	if err != nil {
+		return nil, err
+	}
+	var nl NodeList
+	if err := m.db.Select(&nl, m.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, node := range nl {
+		nodes[node.Id] = node
+	}
+	return nodes, nil
+}
+
+// setReferences replaces the references stored for the node
+func (m *Model) setReferences(nodeId int, refs []int) error {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+	if err := replaceReferences(tx, nodeId, refs); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceReferences stores the references of the node in the transaction
+func replaceReferences(tx *sqlx.Tx, nodeId int, refs []int) error {
+	if _, err := tx.Exec("DELETE FROM node_ref WHERE node_id = $1", nodeId); err != nil {
+		return err
+	}
+	for _, refId := range refs {
+		if _, err := tx.Exec("INSERT INTO node_ref (node_id, ref_id) VALUES ($1, $2)", nodeId, refId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Model) getBacklinks(domainId int, ids []int) (Backlinks, error) {
+	bl := make(Backlinks)
+	if len(ids) == 0 {
+		return bl, nil
+	}
+	query, args, err := sqlx.In(`SELECT node_ref.ref_id AS ref_id, node.* FROM node_ref
+		JOIN node ON node.id = node_ref.node_id
+		WHERE node.domain_id = ? AND node.status = 1 AND node_ref.ref_id IN (?)
+		ORDER BY node.id`, domainId, ids)
+	if err != nil {
+		return nil, err
+	}
+	var links []backlink
+	if err := m.db.Select(&links, m.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		bl[link.RefId] = append(bl[link.RefId], link.Node)
+	}
+	return bl, nil
+}
+
+func (m *Model) mustGetBacklinks(domainId int, ids []int) Backlinks {
+	bl, err := m.getBacklinks(domainId, ids)
+	if err != nil {
+		panic(err)
+	}
+	return bl
+}
+
+// renderNode resolves the references of the node body, renders it and
+// collects the previews of its links
+func (l *ListBoard) renderNode(sc *SiteConfig, node *Node) error {
+	if err := l.renderBody(sc, node); err != nil {
+		return err
+	}
+	node.Previews = l.linkPreviews(sc, node.Body)
+	return nil
+}
+
+// renderBody resolves the references of the node body and renders it
+// without fetching anything
+func (l *ListBoard) renderBody(sc *SiteConfig, node *Node) error {
+	ids := findReferences(node.Body)
+	nodes, err := l.m.getNodesById(node.DomainId, ids)
+	if err != nil {
+		return err
+	}
+	// Keep the order of the body, the map has none
+	node.References = node.References[:0]
+	for _, id := range ids {
+		if _, ok := nodes[id]; ok && id != node.Id {
+			node.References = append(node.References, id)
+		}
+	}
+	node.Rendered = sc.renderText(linkReferences(node.Body, nodes))
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFindReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{"finds both reference styles", ">>12 and #34", []int{12, 34}},
+		{"skips duplicates", ">>12 >>12", []int{12}},
+		{"skips urls and words", "http://example.com/#12 a#34 >>x", nil},
+		{"skips inline code", "`>>12` >>34", []int{34}},
+		{"skips fenced code", "```\n>>12\n```\n#34", []int{34}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findReferences(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findReferences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkReferences(t *testing.T) {
+	nodes := map[int]Node{
+		3: {Id: 3, ParentId: 1, Title: "Item", Level: levelList},
+	}
+	got := linkReferences(">>3 and #4", nodes)
+	if !strings.HasPrefix(got, `[\>\>3](/list/1/item#I3 "Item")`) {
+		t.Errorf("linkReferences() = %v, expected a link to the known node", got)
+	}
+	if !strings.HasSuffix(got, "#4") {
+		t.Errorf("linkReferences() = %v, expected unknown references to stay", got)
+	}
+}
+
+func TestRenderBodyReferences(t *testing.T) {
+	l := &ListBoard{m: newTestModel(t)}
+	var ids []int
+	for i := 0; i < 5; i++ {
+		id, _ := l.m.addNode(&Node{DomainId: 1, Title: "list", Status: statusEnabled, Level: levelRoot})
+		ids = append(ids, id)
+	}
+	node := &Node{Id: ids[0], DomainId: 1, Body: "#" + strconv.Itoa(ids[4]) + " #" + strconv.Itoa(ids[0]) + " #999 #" + strconv.Itoa(ids[2]) + " #" + strconv.Itoa(ids[1])}
+	if err := l.renderBody(&SiteConfig{}, node); err != nil {
+		t.Fatal(err)
+	}
+	// Known references in the order of the body, without the node itself
+	if want := []int{ids[4], ids[2], ids[1]}; !reflect.DeepEqual(node.References, want) {
+		t.Errorf("renderBody() references = %v, want %v", node.References, want)
+	}
+}
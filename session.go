@@ -47,6 +47,7 @@ func (s *Session) getHelpers() template.FuncMap {
 		"slug":     hfSlug,
 		"mod":      hfMod,
 		"gravatar": hfGravatar,
+		"url":      hfUrl,
 	}
 }
 
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+)
+
+const (
+	kindList      = ""
+	kindPoll      = "poll"
+	kindRanked    = "ranked"
+	kindChecklist = "checklist"
+)
+
+// validKind returns the list kind if it's known or the default open list
+func validKind(kind string) string {
+	switch kind {
+	case kindPoll, kindRanked, kindChecklist:
+		return kind
+	}
+	return kindList
+}
+
+// Who may add items to a list
+const (
+	itemsAnyone   = ""
+	itemsTripcode = "tripcode"
+	itemsOwner    = "owner"
+)
+
+// Who may vote in a list
+const (
+	votingOpen     = ""
+	votingTripcode = "tripcode"
+	votingDisabled = "disabled"
+)
+
+// validItems returns the item setting if it's known or the default
+func validItems(items string) string {
+	switch items {
+	case itemsTripcode, itemsOwner:
+		return items
+	}
+	return itemsAnyone
+}
+
+// validVoting returns the voting setting if it's known or the default
+func validVoting(voting string) string {
+	switch voting {
+	case votingTripcode, votingDisabled:
+		return voting
+	}
+	return votingOpen
+}
+
+// ListSettings holds the options of a list stored with its root node
+type ListSettings struct {
+	// Multiple allows picking several options in a poll
+	Multiple bool `json:"multiple,omitempty"`
+	// Items tells who may add items
+	Items string `json:"items,omitempty"`
+	// Voting tells who may vote
+	Voting string `json:"voting,omitempty"`
+	// CommentsOnly turns the votes on items into comments
+	CommentsOnly bool `json:"comments_only,omitempty"`
+	// MaxItems limits the items of a poster, 0 is unlimited
+	MaxItems int `json:"max_items,omitempty"`
+}
+
+// newListSettings builds valid settings from the posted values
+func newListSettings(multiple bool, items, voting string, commentsOnly bool, maxItems int) ListSettings {
+	if maxItems < 0 {
+		maxItems = 0
+	}
+	return ListSettings{
+		Multiple:     multiple,
+		Items:        validItems(items),
+		Voting:       validVoting(voting),
+		CommentsOnly: commentsOnly,
+		MaxItems:     maxItems,
+	}
+}
+
+// ItemsFromTripcodes tells if only posters with a tripcode or an account may
+// add items
+func (ls ListSettings) ItemsFromTripcodes() bool {
+	return ls.Items == itemsTripcode
+}
+
+// ItemsFromOwner tells if only the owner and the editors may add items
+func (ls ListSettings) ItemsFromOwner() bool {
+	return ls.Items == itemsOwner
+}
+
+// VotingNeedsTripcode tells if only voters with a tripcode or an account may
+// vote
+func (ls ListSettings) VotingNeedsTripcode() bool {
+	return ls.Voting == votingTripcode
+}
+
+// VotingDisabled tells if the list takes no votes
+func (ls ListSettings) VotingDisabled() bool {
+	return ls.Voting == votingDisabled
+}
+
+func (ls ListSettings) encode() string {
+	if ls == (ListSettings{}) {
+		return ""
+	}
+	b, err := json.Marshal(ls)
+	if err != nil {
+		log.Printf("Error encoding list settings: %s", err)
+		return ""
+	}
+	return string(b)
+}
+
+// ListSettings decodes the settings of a root node
+func (n Node) ListSettings() ListSettings {
+	var ls ListSettings
+	if n.Settings != "" {
+		if err := json.Unmarshal([]byte(n.Settings), &ls); err != nil {
+			log.Printf("Error decoding settings of node %d: %s", n.Id, err)
+		}
+	}
+	return ls
+}
+
+// IsPoll tells if the list has fixed options
+func (n Node) IsPoll() bool {
+	return n.Kind == kindPoll
+}
+
+// IsRanked tells if the voters rank the items of the list
+func (n Node) IsRanked() bool {
+	return n.Kind == kindRanked
+}
+
+// HasItemVotes tells if the items of the list are voted up and down
+func (n Node) HasItemVotes() bool {
+	return !n.IsPoll() && !n.IsRanked()
+}
+
+// ShowItemVotes tells if the vote form offers up and down votes
+func (n Node) ShowItemVotes() bool {
+	return n.HasItemVotes() && !n.ListSettings().CommentsOnly
+}
+
+// identified tells if the credentials have a tripcode or an account
+func (c Credentials) identified() bool {
+	return c.Tripcode != "" || c.AccountId != 0
+}
+
+// countPosterItems returns the number of items the credentials posted in
+// the list
+func (m *Model) countPosterItems(list *Node, c Credentials) (int, error) {
+	var count int
+	err := m.db.Get(&count, `SELECT COUNT(*) FROM node WHERE domain_id = $1 AND parent_id = $2 AND level = $3 AND status = 1 AND (
+			(tripcode != '' AND (tripcode = $4 OR tripcode = $5))
+			OR (account_id != 0 AND account_id = $6)
+		)`, list.DomainId, list.Id, levelList, c.Tripcode, c.ClassicTripcode, c.AccountId)
+	return count, err
+}
+
+// itemErrors checks that the credentials may add an item to the list
+func (l *ListBoard) itemErrors(list *Node, c Credentials, ln *Language) (ValidationErrors, error) {
+	ls := list.ListSettings()
+	if ls.ItemsFromOwner() && !l.canEditList(list, c) {
+		return ValidationErrors{ln.Lang("Only the editors of the list can add items")}, nil
+	}
+	if (ls.ItemsFromTripcodes() || ls.MaxItems > 0) && !c.identified() {
+		return ValidationErrors{ln.Lang("Enter a tripcode password or log in to add items")}, nil
+	}
+	if ls.MaxItems > 0 {
+		count, err := l.m.countPosterItems(list, c)
+		if err != nil {
+			return nil, err
+		}
+		if count >= ls.MaxItems {
+			return ValidationErrors{ln.Lang("You have added the most items allowed in this list")}, nil
+		}
+	}
+	return nil, nil
+}
+
+// votingErrors checks that the voter may vote in the list
+func votingErrors(list *Node, identified bool, ln *Language) ValidationErrors {
+	ls := list.ListSettings()
+	if ls.VotingDisabled() {
+		return ValidationErrors{ln.Lang("Voting is disabled in this list")}
+	}
+	if ls.VotingNeedsTripcode() && !identified {
+		return ValidationErrors{ln.Lang("Enter a tripcode password or log in to vote")}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import "testing"
+
+func TestListSettingsEncode(t *testing.T) {
+	if got := (ListSettings{}).encode(); got != "" {
+		t.Errorf("encode() of the defaults = %q, want empty", got)
+	}
+	ls := newListSettings(false, "owner", "unknown", true, -3)
+	got := Node{Settings: ls.encode()}.ListSettings()
+	want := ListSettings{Items: itemsOwner, Voting: votingOpen, CommentsOnly: true}
+	if got != want {
+		t.Errorf("ListSettings() = %+v, want %+v", got, want)
+	}
+}
+
+func TestItemErrors(t *testing.T) {
+	l := &ListBoard{m: newTestModel(t)}
+	ln := &Language{}
+	owner := Credentials{Tripcode: "!owner"}
+	poster := Credentials{Tripcode: "!poster"}
+	newList := func(ls ListSettings) *Node {
+		id, _ := l.m.addNode(&Node{DomainId: 1, Title: "list", Tripcode: owner.Tripcode, Settings: ls.encode(), Status: statusEnabled, Level: levelRoot})
+		list, _ := l.m.getNode(1, id)
+		return list
+	}
+	tests := []struct {
+		name   string
+		ls     ListSettings
+		c      Credentials
+		errors int
+	}{
+		{"anyone", ListSettings{}, Credentials{}, 0},
+		{"tripcode holders without a tripcode", ListSettings{Items: itemsTripcode}, Credentials{}, 1},
+		{"tripcode holders with a tripcode", ListSettings{Items: itemsTripcode}, poster, 0},
+		{"tripcode holders with an account", ListSettings{Items: itemsTripcode}, Credentials{AccountId: 1}, 0},
+		{"owner only by a poster", ListSettings{Items: itemsOwner}, poster, 1},
+		{"owner only by the owner", ListSettings{Items: itemsOwner}, owner, 0},
+		{"limited without a tripcode", ListSettings{MaxItems: 1}, Credentials{}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors, err := l.itemErrors(newList(tt.ls), tt.c, ln)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(errors) != tt.errors {
+				t.Errorf("itemErrors() = %v, want %d errors", errors, tt.errors)
+			}
+		})
+	}
+
+	t.Run("limited per poster", func(t *testing.T) {
+		list := newList(ListSettings{MaxItems: 2})
+		for i := 0; i < 2; i++ {
+			if errors, _ := l.itemErrors(list, poster, ln); len(errors) != 0 {
+				t.Fatalf("itemErrors() for item %d = %v", i+1, errors)
+			}
+			l.m.addNode(&Node{DomainId: 1, ParentId: list.Id, Title: "item", Tripcode: poster.Tripcode, Status: statusEnabled, Level: levelList})
+		}
+		if errors, _ := l.itemErrors(list, poster, ln); len(errors) != 1 {
+			t.Errorf("itemErrors() over the limit = %v, want 1 error", errors)
+		}
+		if errors, _ := l.itemErrors(list, owner, ln); len(errors) != 0 {
+			t.Errorf("itemErrors() for another poster = %v, want none", errors)
+		}
+	})
+}
+
+func TestVotingErrors(t *testing.T) {
+	ln := &Language{}
+	tests := []struct {
+		voting     string
+		identified bool
+		errors     int
+	}{
+		{votingOpen, false, 0},
+		{votingTripcode, false, 1},
+		{votingTripcode, true, 0},
+		{votingDisabled, true, 1},
+	}
+	for _, tt := range tests {
+		list := &Node{Settings: ListSettings{Voting: tt.voting}.encode()}
+		if errors := votingErrors(list, tt.identified, ln); len(errors) != tt.errors {
+			t.Errorf("votingErrors(%q, %v) = %v, want %d errors", tt.voting, tt.identified, errors, tt.errors)
+		}
+	}
+}
@@ -42,6 +42,13 @@ func (sg *SpamGuard) CanPost(id string) bool {
 	return result
 }
 
+// Release forgets the last post of the id when it was not saved
+func (sg *SpamGuard) Release(id string) {
+	sg.mutex.Lock()
+	delete(sg.posts, id)
+	sg.mutex.Unlock()
+}
+
 func (sg *SpamGuard) clean(now time.Time) {
 	for key, expires := range sg.posts {
 		if expires.Before(now) {
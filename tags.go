@@ -0,0 +1,210 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/gosimple/slug"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	maxTags      = 10
+	maxTagLength = 32
+	tagCloudSize = 50
+	// tagCloudWeights is the number of font sizes in the tag cloud
+	tagCloudWeights = 5
+)
+
+// Tag is a label of a list
+type Tag struct {
+	NodeId int    `db:"node_id" json:"-"`
+	Name   string `db:"tag" json:"name"`
+	Slug   string `db:"slug" json:"slug"`
+	Count  int    `db:"count" json:"count,omitempty"`
+	Weight int    `db:"-" json:"-"`
+}
+
+// NodeTags holds the tags of lists by their id
+type NodeTags map[int][]Tag
+
+// parseTags returns the unique comma separated tags of text
+func parseTags(text string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(text, ",") {
+		tag := strings.ToLower(strings.Join(strings.Fields(part), " "))
+		s := slug.Make(tag)
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func validateTags(tags []string, ln *Language) ValidationErrors {
+	errors := ValidationErrors{}
+	if len(tags) > maxTags {
+		errors = append(errors, ln.Lang("Please, use at most 10 tags"))
+	}
+	for _, tag := range tags {
+		if len(tag) > maxTagLength {
+			errors = append(errors, ln.Lang("Tags must be shorter than 32 characters"))
+			break
+		}
+	}
+	return errors
+}
+
+// tagSlugs splits the tags of a tag page path like go+web
+func tagSlugs(path string) []string {
+	var slugs []string
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(path, "+") {
+		s = slug.Make(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		slugs = append(slugs, s)
+	}
+	return slugs
+}
+
+// TagList returns the tags in the format of the form input
+func (n Node) TagList() string {
+	return strings.Join(n.Tags, ", ")
+}
+
+// setCloudWeights spreads the tag counts over the cloud font sizes
+func setCloudWeights(tags []Tag) {
+	max := 0
+	for _, tag := range tags {
+		if tag.Count > max {
+			max = tag.Count
+		}
+	}
+	for i := range tags {
+		tags[i].Weight = 1
+		if max > 1 {
+			tags[i].Weight = 1 + (tags[i].Count-1)*(tagCloudWeights-1)/(max-1)
+		}
+	}
+}
+
+func (m *Model) setTags(domainId, nodeId int, tags []string) error {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+	if err := replaceTags(tx, domainId, nodeId, tags); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceTags stores the tags of the node in the transaction
+func replaceTags(tx *sqlx.Tx, domainId, nodeId int, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM node_tag WHERE node_id = $1", nodeId); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		_, err := tx.Exec("INSERT INTO node_tag (node_id, domain_id, tag, slug) VALUES ($1, $2, $3, $4)",
+			nodeId, domainId, tag, slug.Make(tag))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Model) getTags(domainId int, ids []int) (NodeTags, error) {
+	nt := make(NodeTags)
+	if len(ids) == 0 {
+		return nt, nil
+	}
+	query, args, err := sqlx.In("SELECT node_id, tag, slug FROM node_tag WHERE domain_id = ? AND node_id IN (?) ORDER BY tag", domainId, ids)
+	if err != nil {
+		return nil, err
+	}
+	var tags []Tag
+	if err := m.db.Select(&tags, m.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		nt[tag.NodeId] = append(nt[tag.NodeId], tag)
+	}
+	return nt, nil
+}
+
+func (m *Model) mustGetTags(domainId int, ids []int) NodeTags {
+	nt, err := m.getTags(domainId, ids)
+	if err != nil {
+		panic(err)
+	}
+	return nt
+}
+
+// getTagNames returns the tag names of the lists, used to fill the forms
+func (m *Model) getTagNames(domainId, nodeId int) ([]string, error) {
+	var names []string
+	err := m.db.Select(&names, "SELECT tag FROM node_tag WHERE domain_id = $1 AND node_id = $2 ORDER BY tag", domainId, nodeId)
+	return names, err
+}
+
+// getTagCloud returns the most used tags of the domain sorted by name
+func (m *Model) getTagCloud(domainId, limit int) ([]Tag, error) {
+	var tags []Tag
+	err := m.db.Select(&tags, `SELECT MIN(node_tag.tag) AS tag, node_tag.slug AS slug, COUNT(*) AS count FROM node_tag
+		JOIN node ON node.id = node_tag.node_id
+		WHERE node_tag.domain_id = $1 AND node.status = 1
+		GROUP BY node_tag.slug
+		ORDER BY count DESC, slug
+		LIMIT $2`, domainId, limit)
+	if err != nil {
+		return nil, err
+	}
+	setCloudWeights(tags)
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Slug < tags[j].Slug
+	})
+	return tags, nil
+}
+
+func (l *ListBoard) tagHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+	slugs := tagSlugs(mux.Vars(r)["tags"])
+	if len(slugs) == 0 {
+		return HTTPError{Message: "Tag not found", Code: http.StatusNotFound}
+	}
+	f := parseIndexFilter(r.URL.Query())
+	f.Tags = slugs
+	s := l.session(w, r, sc, l.tp.Get(sc.Language))
+	s.AddPath("/", s.Lang("Home"))
+	s.AddPath("", strings.Join(slugs, " + "))
+	s.Set("Subtitle", strings.Join(slugs, ", "))
+	s.Set("TagPath", strings.Join(slugs, "+"))
+	s.Set("TagSlugs", slugs)
+	if err := l.setListsSession(s, r, sc, f); err != nil {
+		return err
+	}
+	return s.render(w, r, sc.templatePath("layout.html"), sc.templatePath("index.html"))
+}
+
+func (l *ListBoard) tagFeedHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+	slugs := tagSlugs(mux.Vars(r)["tags"])
+	if len(slugs) == 0 {
+		return HTTPError{Message: "Tag not found", Code: http.StatusNotFound}
+	}
+	nodes, err := l.m.getLists(sc.DomainId, IndexFilter{Sort: sortNewest, Tags: slugs}, feedSize, 0)
+	if err != nil {
+		return err
+	}
+	return l.feed(w, sc, "http://"+r.Host, nodes)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	got := parseTags(" Go,  Web   Apps,go,, !!")
+	want := []string{"go", "web apps"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTags() = %v, want %v", got, want)
+	}
+}
+
+func TestTagSlugs(t *testing.T) {
+	got := tagSlugs("go+web-apps+go+")
+	want := []string{"go", "web-apps"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tagSlugs() = %v, want %v", got, want)
+	}
+}
+
+func TestSetCloudWeights(t *testing.T) {
+	tags := []Tag{{Count: 1}, {Count: 5}, {Count: 9}}
+	setCloudWeights(tags)
+	weights := []int{tags[0].Weight, tags[1].Weight, tags[2].Weight}
+	if !reflect.DeepEqual(weights, []int{1, 3, 5}) {
+		t.Errorf("setCloudWeights() = %v, want [1 3 5]", weights)
+	}
+}
+
+func TestModelTags(t *testing.T) {
+	m := newTestModel(t)
+	first, _ := m.addNode(&Node{DomainId: 1, Title: "First", Tags: []string{"go", "web apps"}, Status: statusEnabled, Level: levelRoot})
+	second, _ := m.addNode(&Node{DomainId: 1, Title: "Second", Tags: []string{"go"}, Status: statusEnabled, Level: levelRoot})
+	m.addNode(&Node{DomainId: 2, Title: "Other", Tags: []string{"go"}, Status: statusEnabled, Level: levelRoot})
+
+	nodes, err := m.getLists(1, IndexFilter{Sort: sortNewest, Tags: []string{"go"}}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodes.Ids(); !reflect.DeepEqual(got, []int{second, first}) {
+		t.Errorf("getLists(go) = %v, want [%d %d]", got, second, first)
+	}
+	nodes, _ = m.getLists(1, IndexFilter{Tags: []string{"go", "web-apps"}}, 10, 0)
+	if got := nodes.Ids(); !reflect.DeepEqual(got, []int{first}) {
+		t.Errorf("getLists(go, web-apps) = %v, want [%d]", got, first)
+	}
+	if total, _ := m.getListsTotal(1, IndexFilter{Tags: []string{"go"}}); total != 2 {
+		t.Errorf("getListsTotal() = %d, want 2", total)
+	}
+	cloud, err := m.getTagCloud(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cloud) != 2 || cloud[0].Slug != "go" || cloud[0].Count != 2 {
+		t.Errorf("getTagCloud() = %+v", cloud)
+	}
+}
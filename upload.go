@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	defaultUploadDir      = "./public_html/uploads/"
+	defaultUploadURL      = "/uploads/"
+	defaultUploadMaxSize  = 5 << 20
+	defaultThumbnailSize  = 320
+	uploadJpegQuality     = 90
+	uploadThumbnailSuffix = "_t"
+	defaultUploadBlock    = "1s"
+	// maxImagePixels limits the decoded size of the images
+	maxImagePixels = 40 << 20
+)
+
+var uploadExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+type UploadConfig struct {
+	Dir           string `json:"dir"`
+	URL           string `json:"url"`
+	MaxSize       int64  `json:"max_size"`
+	ThumbnailSize int    `json:"thumbnail_size"`
+	// BlockExpire is the time between two uploads from the same address
+	BlockExpire string `json:"block_expire"`
+}
+
+// BlobStore keeps the uploaded files
+type BlobStore interface {
+	Exists(name string) bool
+	Put(name string, data []byte) error
+	URL(name string) string
+}
+
+type LocalStore struct {
+	dir string
+	url string
+}
+
+type uploadResult struct {
+	URL       string `json:"url"`
+	Thumbnail string `json:"thumbnail"`
+	Markdown  string `json:"markdown"`
+}
+
+func NewLocalStore(dir, url string) *LocalStore {
+	return &LocalStore{dir: dir, url: url}
+}
+
+func (ls *LocalStore) Exists(name string) bool {
+	_, err := os.Stat(filepath.Join(ls.dir, name))
+	return err == nil
+}
+
+func (ls *LocalStore) Put(name string, data []byte) error {
+	if err := os.MkdirAll(ls.dir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(ls.dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(ls.dir, name))
+}
+
+func (ls *LocalStore) URL(name string) string {
+	return ls.url + name
+}
+
+func (uc *UploadConfig) setDefaults() {
+	if uc.Dir == "" {
+		uc.Dir = defaultUploadDir
+	}
+	if uc.URL == "" {
+		uc.URL = defaultUploadURL
+	}
+	if uc.MaxSize == 0 {
+		uc.MaxSize = defaultUploadMaxSize
+	}
+	if uc.ThumbnailSize == 0 {
+		uc.ThumbnailSize = defaultThumbnailSize
+	}
+	if uc.BlockExpire == "" {
+		uc.BlockExpire = defaultUploadBlock
+	}
+}
+
+// cleanImage re-encodes the image which drops EXIF and other metadata. The
+// size is checked before decoding so small files can't claim huge images.
+func cleanImage(data []byte, contentType string) ([]byte, image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxImagePixels {
+		return nil, nil, errUnsupportedImage
+	}
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/gif":
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		// Every frame may cover the whole canvas
+		if len(g.Image) > maxImagePixels/(config.Width*config.Height) {
+			return nil, nil, errUnsupportedImage
+		}
+		if err := gif.EncodeAll(&buf, g); err != nil {
+			return nil, nil, err
+		}
+		return buf.Bytes(), g.Image[0], nil
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		err = png.Encode(&buf, img)
+		return buf.Bytes(), img, err
+	default:
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: uploadJpegQuality})
+		return buf.Bytes(), img, err
+	}
+}
+
+// thumbnail scales the image to fit in a size x size box
+func thumbnail(img image.Image, size int) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w > h {
+			w, h = size, h*size/w
+		} else {
+			w, h = w*size/h, size
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: uploadJpegQuality})
+	return buf.Bytes(), err
+}
+
+// storeImage validates, cleans and stores the image with its thumbnail.
+// Files are named after the hash of the upload so duplicates are stored once.
+func storeImage(store BlobStore, data []byte, thumbnailSize int) (*uploadResult, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := uploadExtensions[contentType]
+	if !ok {
+		return nil, errUnsupportedImage
+	}
+	hash := sha256.Sum256(data)
+	name := hex.EncodeToString(hash[:16])
+	full := name + ext
+	thumb := name + uploadThumbnailSuffix + ".jpg"
+	if !store.Exists(full) || !store.Exists(thumb) {
+		clean, img, err := cleanImage(data, contentType)
+		if err != nil {
+			return nil, errUnsupportedImage
+		}
+		t, err := thumbnail(img, thumbnailSize)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.Put(thumb, t); err != nil {
+			return nil, err
+		}
+		if err := store.Put(full, clean); err != nil {
+			return nil, err
+		}
+	}
+	return &uploadResult{
+		URL:       store.URL(full),
+		Thumbnail: store.URL(thumb),
+		Markdown:  "[![Image](" + store.URL(thumb) + ")](" + store.URL(full) + ")",
+	}, nil
+}
+
+// limitUpload limits the size of the upload before the form is parsed by
+// the CSRF check
+func (l *ListBoard) limitUpload(fn appHandler) appHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		r.Body = http.MaxBytesReader(w, r.Body, l.config.Uploads.MaxSize+1<<20)
+		return fn(w, r)
+	}
+}
+
+// uploadHandler stores the posted image and returns the markdown snippet
+// linking to it
+func (l *ListBoard) uploadHandler(w http.ResponseWriter, r *http.Request) error {
+	sc := l.config.getSiteConfig(l.getToken(r))
+	tr := l.tp.Get(sc.Language)
+	uc := l.config.Uploads
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{tr.Lang("Image is too large or missing")}})
+	}
+	defer file.Close()
+	if !l.uploadSg.CanPost(remoteHost(r)) {
+		return writeJSON(w, http.StatusTooManyRequests, apiErrors{ValidationErrors{tr.Lang("Please wait before posting again")}})
+	}
+	if header.Size > uc.MaxSize {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{tr.Lang("Image is too large or missing")}})
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	result, err := storeImage(l.store, data, uc.ThumbnailSize)
+	if err == errUnsupportedImage {
+		return writeJSON(w, http.StatusBadRequest, apiErrors{ValidationErrors{tr.Lang("Only JPEG, PNG and GIF images are allowed")}})
+	}
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+type memoryStore struct {
+	files map[string][]byte
+	puts  int
+}
+
+func (ms *memoryStore) Exists(name string) bool {
+	_, ok := ms.files[name]
+	return ok
+}
+
+func (ms *memoryStore) Put(name string, data []byte) error {
+	ms.puts++
+	ms.files[name] = data
+	return nil
+}
+
+func (ms *memoryStore) URL(name string) string {
+	return "/uploads/" + name
+}
+
+func testPng(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 640, 320))
+	img.Set(1, 1, color.RGBA{255, 0, 0, 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// framedGif returns a GIF of tiny frames on a large canvas
+func framedGif(t *testing.T, frames, size int) []byte {
+	g := &gif.GIF{Config: image.Config{Width: size, Height: size}}
+	for i := 0; i < frames; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black, color.White}))
+		g.Delay = append(g.Delay, 0)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// resizedPng returns a small PNG claiming the width and height in its header
+func resizedPng(t *testing.T, width, height uint32) []byte {
+	data := testPng(t)
+	// The IHDR chunk follows the 8 byte signature and its length and type
+	ihdr := data[12:29]
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func TestStoreImage(t *testing.T) {
+	t.Run("stores image and thumbnail once", func(t *testing.T) {
+		store := &memoryStore{files: make(map[string][]byte)}
+		data := testPng(t)
+		result, err := storeImage(store, data, 320)
+		if err != nil {
+			t.Fatalf("storeImage() error = %v", err)
+		}
+		if store.puts != 2 {
+			t.Errorf("Expected 2 stored files, got %d", store.puts)
+		}
+		want := "[![Image](" + result.Thumbnail + ")](" + result.URL + ")"
+		if result.Markdown != want {
+			t.Errorf("Markdown = %v, want %v", result.Markdown, want)
+		}
+		if _, err := storeImage(store, data, 320); err != nil {
+			t.Fatalf("storeImage() error = %v", err)
+		}
+		if store.puts != 2 {
+			t.Errorf("Expected duplicate upload not to be stored again")
+		}
+	})
+	t.Run("scales the thumbnail", func(t *testing.T) {
+		store := &memoryStore{files: make(map[string][]byte)}
+		result, _ := storeImage(store, testPng(t), 320)
+		thumb, _, err := image.Decode(bytes.NewReader(store.files[result.Thumbnail[len("/uploads/"):]]))
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		if b := thumb.Bounds(); b.Dx() != 320 || b.Dy() != 160 {
+			t.Errorf("Thumbnail size = %v, want 320x160", b)
+		}
+	})
+	t.Run("rejects huge images before decoding", func(t *testing.T) {
+		store := &memoryStore{files: make(map[string][]byte)}
+		if _, err := storeImage(store, resizedPng(t, 100000, 100000), 320); err != errUnsupportedImage {
+			t.Errorf("storeImage() error = %v, want %v", err, errUnsupportedImage)
+		}
+	})
+	t.Run("rejects gifs with too many frames", func(t *testing.T) {
+		store := &memoryStore{files: make(map[string][]byte)}
+		if _, err := storeImage(store, framedGif(t, 2, 64), 320); err != nil {
+			t.Errorf("storeImage() of a small gif error = %v", err)
+		}
+		if _, err := storeImage(store, framedGif(t, 3, 4096), 320); err != errUnsupportedImage {
+			t.Errorf("storeImage() error = %v, want %v", err, errUnsupportedImage)
+		}
+	})
+	t.Run("rejects other files", func(t *testing.T) {
+		store := &memoryStore{files: make(map[string][]byte)}
+		if _, err := storeImage(store, []byte("<html></html>"), 320); err != errUnsupportedImage {
+			t.Errorf("storeImage() error = %v, want %v", err, errUnsupportedImage)
+		}
+	})
+}
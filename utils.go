@@ -1,14 +1,17 @@
 package main
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"net"
+	"net/http"
 	"time"
 
 	"github.com/aquilax/tripcode"
 	"github.com/gosimple/slug"
-	"github.com/microcosm-cc/bluemonday"
-	"github.com/russross/blackfriday"
 )
 
 func hfTime(t time.Time) string {
@@ -19,14 +22,35 @@ func hfSlug(s string) string {
 	return slug.Make(s) + ".html"
 }
 
+func hfUrl(node Node) string {
+	return getUrl("", node)
+}
+
 func hfMod(n int, mod int) int {
 	return n % mod
 }
 
+const (
+	secureTripcodePrefix = "!!"
+	secureTripcodeLength = 10
+)
+
 func getTripcode(s string) string {
 	return tripcode.Tripcode(s)
 }
 
+// getSecureTripcode returns a HMAC based tripcode which can only be
+// reproduced by an install knowing the same secret
+func getSecureTripcode(secret, s string) string {
+	if s == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(s))
+	code := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	return secureTripcodePrefix + code[:secureTripcodeLength]
+}
+
 func getVote(t string) int {
 	if t == "y" {
 		return 1
@@ -41,31 +65,6 @@ func inHoneypot(t string) bool {
 	return len(t) > 0
 }
 
-func renderText(t string) string {
-	extensions := 0
-	extensions |= blackfriday.HTML_USE_XHTML
-	extensions |= blackfriday.HTML_USE_SMARTYPANTS
-	extensions |= blackfriday.HTML_SMARTYPANTS_FRACTIONS
-	extensions |= blackfriday.HTML_SMARTYPANTS_LATEX_DASHES
-	extensions |= blackfriday.EXTENSION_AUTOLINK
-	extensions |= blackfriday.EXTENSION_HARD_LINE_BREAK
-	extensions |= blackfriday.EXTENSION_NO_INTRA_EMPHASIS
-	extensions |= blackfriday.EXTENSION_TABLES
-	extensions |= blackfriday.EXTENSION_FENCED_CODE
-	extensions |= blackfriday.EXTENSION_STRIKETHROUGH
-	extensions |= blackfriday.EXTENSION_SPACE_HEADERS
-	extensions |= blackfriday.EXTENSION_HEADER_IDS
-
-	htmlFlags := 0 |
-		blackfriday.HTML_USE_XHTML |
-		blackfriday.HTML_USE_SMARTYPANTS |
-		blackfriday.HTML_SMARTYPANTS_FRACTIONS
-
-	renderer := blackfriday.HtmlRenderer(htmlFlags, "", "")
-	unsafe := blackfriday.Markdown([]byte(t), renderer, extensions)
-	return string(bluemonday.UGCPolicy().SanitizeBytes(unsafe))
-}
-
 func hfGravatar(tripcode string) string {
 	if tripcode == "" {
 		return "http://www.gravatar.com/avatar/00000000000000000000000000000000?d=retro"
@@ -73,3 +72,13 @@ func hfGravatar(tripcode string) string {
 	hash := md5.Sum([]byte(tripcode))
 	return "http://www.gravatar.com/avatar/" + hex.EncodeToString(hash[:]) + "?d=retro"
 }
+
+// remoteHost returns the address of the visitor without the port, which
+// changes with every connection
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetSecureTripcode(t *testing.T) {
+	t.Run("returns empty tripcode for empty password", func(t *testing.T) {
+		if got := getSecureTripcode("secret", ""); got != "" {
+			t.Errorf("getSecureTripcode() = %v, want empty string", got)
+		}
+	})
+	t.Run("secure tripcode is prefixed and stable", func(t *testing.T) {
+		got := getSecureTripcode("secret", "password")
+		if !strings.HasPrefix(got, secureTripcodePrefix) {
+			t.Errorf("getSecureTripcode() = %v, expected %s prefix", got, secureTripcodePrefix)
+		}
+		if len(got) != len(secureTripcodePrefix)+secureTripcodeLength {
+			t.Errorf("getSecureTripcode() = %v, unexpected length", got)
+		}
+		if again := getSecureTripcode("secret", "password"); again != got {
+			t.Errorf("getSecureTripcode() = %v, want %v", again, got)
+		}
+	})
+	t.Run("secure tripcode depends on the secret", func(t *testing.T) {
+		if getSecureTripcode("secret", "password") == getSecureTripcode("other", "password") {
+			t.Errorf("Expected different tripcodes for different secrets")
+		}
+	})
+}
+
+func TestConfigTripcode(t *testing.T) {
+	t.Run("uses classic tripcode without secret", func(t *testing.T) {
+		c := &Config{}
+		if got, want := c.tripcode("password"), getTripcode("password"); got != want {
+			t.Errorf("tripcode() = %v, want %v", got, want)
+		}
+	})
+	t.Run("uses secure tripcode with secret", func(t *testing.T) {
+		c := &Config{TripcodeSecret: "secret"}
+		if got, want := c.tripcode("password"), getSecureTripcode("secret", "password"); got != want {
+			t.Errorf("tripcode() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestRemoteHost(t *testing.T) {
+	for remoteAddr, want := range map[string]string{"1.2.3.4:5678": "1.2.3.4", "[::1]:80": "::1", "unix": "unix"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		if got := remoteHost(r); got != want {
+			t.Errorf("remoteHost(%q) = %q, want %q", remoteAddr, got, want)
+		}
+	}
+}